@@ -0,0 +1,338 @@
+package gormx
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	AggTag    = "agg"    // 聚合函数标签，e.g. agg:"sum;column:amount"
+	GroupTag  = "group"  // 分组标签，e.g. group:"date_trunc:day;column:created_at"
+	HavingTag = "having" // 聚合结果过滤，e.g. having:"gte:100"
+)
+
+const (
+	AggCount         = "count"         // COUNT(column)，未设置 column 时为 COUNT(*)
+	AggCountDistinct = "countdistinct" // COUNT(DISTINCT column)
+	AggSum           = "sum"           // SUM(column)
+	AggAvg           = "avg"           // AVG(column)
+	AggMin           = "min"           // MIN(column)
+	AggMax           = "max"           // MAX(column)
+)
+
+// dateTruncUnits date_trunc 支持的时间粒度
+var dateTruncUnits = map[string]bool{
+	"year": true, "month": true, "week": true, "day": true, "hour": true, "minute": true,
+}
+
+// aggregatePlans 缓存已编译的聚合计划，key 为 planKey
+var aggregatePlans sync.Map
+
+type (
+	// aggregatePlan 结果结构体编译后的聚合查询
+	aggregatePlan struct {
+		selects []string
+		groups  []string
+		having  []GormClause
+	}
+
+	// aggregateTag agg/group 标签内容
+	aggregateTag struct {
+		Func      string
+		Column    string
+		Table     string
+		DateTrunc string
+	}
+)
+
+// Aggregate 按结果结构体 R 的 agg/group/having 标签执行分组统计，返回每组一行
+// db 需指定 Model 或 Table；q 为 search 查询对象，scopes 可传入数据权限等条件，e.g.
+//
+//	type DailyAmount struct {
+//		Day    time.Time `group:"date_trunc:day;column:created_at"`
+//		Status int64     `group:"column:status"`
+//		Amount float64   `agg:"sum;column:amount" having:"gt:0"`
+//		Count  int64     `agg:"count"`
+//	}
+//	list, err := gormx.Aggregate[DailyAmount](db.Model(&Order{}), req, gormx.PermissionData("order", p))
+func Aggregate[R any](db *gorm.DB, q interface{}, scopes ...func(db *gorm.DB) *gorm.DB) ([]R, error) {
+	tx := db
+	if q != nil {
+		tx = tx.Scopes(MakeCondition(q, ""))
+	}
+	rows, err := tx.Scopes(scopes...).Scopes(AggregateScope(new(R))).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sch, err := schema.Parse(new(R), schemaCache, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]*schema.Field, len(columns))
+	for i, c := range columns {
+		fields[i] = sch.LookUpField(c)
+	}
+	// 逐列按字段类型赋值，SQLite 的时间截断结果为字符串，由 field.Set 转换
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var list []R
+	ctx := db.Statement.Context
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		var r R
+		rv := reflect.ValueOf(&r).Elem()
+		for i, f := range fields {
+			if f == nil || values[i] == nil {
+				continue
+			}
+			if err := f.Set(ctx, rv, values[i]); err != nil {
+				return nil, err
+			}
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// AggregateScope 根据结果结构体的 agg/group/having 标签生成 SELECT、GROUP BY、HAVING 及按分组排序
+// 标签错误时通过 db.AddError 返回
+func AggregateScope(result interface{}) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		typ := reflect.TypeOf(result)
+		for typ != nil && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice) {
+			typ = typ.Elem()
+		}
+		if typ == nil || typ.Kind() != reflect.Struct {
+			_ = db.AddError(fmt.Errorf("聚合结果必须为结构体: %v", typ))
+			return db
+		}
+		name, d := resolveDialect(dialectorName(db))
+		plan, err := loadAggregatePlan(db, name, d, typ)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		db = db.Select(strings.Join(plan.selects, ", "))
+		// 分组表达式已引用，按原样写入，避免 db.Group 再次引用单个列名
+		groupBy := clause.GroupBy{Columns: make([]clause.Column, len(plan.groups))}
+		for i, g := range plan.groups {
+			groupBy.Columns[i] = clause.Column{Name: g, Raw: true}
+		}
+		db = db.Clauses(groupBy)
+		for _, h := range plan.having {
+			db = db.Having(h.Query, h.Args...)
+		}
+		for _, g := range plan.groups {
+			db = db.Order(g)
+		}
+		return db
+	}
+}
+
+func loadAggregatePlan(db *gorm.DB, name string, d SearchDialect, typ reflect.Type) (*aggregatePlan, error) {
+	key := planKey{typ: typ, dialect: name}
+	if p, ok := aggregatePlans.Load(key); ok {
+		return p.(*aggregatePlan), nil
+	}
+	p, err := compileAggregatePlan(db, d, typ)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := aggregatePlans.LoadOrStore(key, p)
+	return actual.(*aggregatePlan), nil
+}
+
+// compileAggregatePlan 解析结果结构体，结果列别名取 gorm 字段列名，以便 Scan 映射
+func compileAggregatePlan(db *gorm.DB, d SearchDialect, typ reflect.Type) (*aggregatePlan, error) {
+	sch, err := schema.Parse(reflect.New(typ).Interface(), &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	p := &aggregatePlan{}
+	for _, field := range sch.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		alias := d.Quote(field.DBName)
+		if tag, ok := field.Tag.Lookup(GroupTag); ok {
+			t := makeAggregateTag(tag)
+			if t.Column == "" {
+				return nil, fmt.Errorf("group 标签错误 %s: 缺少 column", field.Name)
+			}
+			expr := columnRef(d, t.Table, t.Column)
+			if t.DateTrunc != "" {
+				if expr, err = dateTrunc(d.Name(), t.DateTrunc, expr); err != nil {
+					return nil, fmt.Errorf("group 标签错误 %s: %v", field.Name, err)
+				}
+			}
+			p.selects = append(p.selects, expr+" AS "+alias)
+			p.groups = append(p.groups, expr)
+			continue
+		}
+		tag, ok := field.Tag.Lookup(AggTag)
+		if !ok {
+			continue
+		}
+		t := makeAggregateTag(tag)
+		expr, err := aggregateExpr(d, t)
+		if err != nil {
+			return nil, fmt.Errorf("agg 标签错误 %s: %v", field.Name, err)
+		}
+		p.selects = append(p.selects, expr+" AS "+alias)
+		if having, ok := field.Tag.Lookup(HavingTag); ok {
+			clause, err := havingClause(expr, having)
+			if err != nil {
+				return nil, fmt.Errorf("having 标签错误 %s: %v", field.Name, err)
+			}
+			p.having = append(p.having, clause)
+		}
+	}
+	if len(p.selects) == 0 {
+		return nil, fmt.Errorf("聚合结果 %s 未设置 agg/group 标签", typ.Name())
+	}
+	return p, nil
+}
+
+// makeAggregateTag 解析 agg/group 标签，首段不含 ":" 时为聚合函数名
+func makeAggregateTag(tag string) *aggregateTag {
+	r := &aggregateTag{}
+	for i, t := range strings.Split(tag, ";") {
+		ts := strings.SplitN(strings.TrimSpace(t), ":", 2)
+		if i == 0 && len(ts) == 1 {
+			r.Func = strings.ToLower(ts[0])
+			continue
+		}
+		if len(ts) < 2 {
+			continue
+		}
+		switch ts[0] {
+		case "column":
+			r.Column = ts[1]
+		case "table":
+			r.Table = ts[1]
+		case "date_trunc":
+			r.DateTrunc = strings.ToLower(ts[1])
+		}
+	}
+	return r
+}
+
+// aggregateExpr 生成聚合表达式
+func aggregateExpr(d SearchDialect, t *aggregateTag) (string, error) {
+	if t.Column == "" {
+		if t.Func == AggCount {
+			return "COUNT(*)", nil
+		}
+		return "", fmt.Errorf("缺少 column")
+	}
+	column := columnRef(d, t.Table, t.Column)
+	switch t.Func {
+	case AggCount, AggSum, AggAvg, AggMin, AggMax:
+		return fmt.Sprintf("%s(%s)", strings.ToUpper(t.Func), column), nil
+	case AggCountDistinct:
+		return fmt.Sprintf("COUNT(DISTINCT %s)", column), nil
+	}
+	return "", fmt.Errorf("不支持的聚合函数 %q", t.Func)
+}
+
+// havingClause 生成 HAVING 条件，having 为 op:value，多个条件以 ";" 分隔并以 AND 连接
+func havingClause(expr, having string) (GormClause, error) {
+	var exprs []string
+	var args []interface{}
+	for _, h := range strings.Split(having, ";") {
+		op, value, ok := strings.Cut(strings.TrimSpace(h), ":")
+		if !ok {
+			return GormClause{}, fmt.Errorf("格式须为 op:value")
+		}
+		var sqlOp string
+		switch strings.ToLower(op) {
+		case Exact:
+			sqlOp = "="
+		case NotEqual:
+			sqlOp = "<>"
+		case Greater:
+			sqlOp = ">"
+		case GreaterEq:
+			sqlOp = ">="
+		case Less:
+			sqlOp = "<"
+		case LessEq:
+			sqlOp = "<="
+		default:
+			return GormClause{}, fmt.Errorf("不支持的操作符 %q", op)
+		}
+		exprs = append(exprs, fmt.Sprintf("%s %s ?", expr, sqlOp))
+		args = append(args, havingValue(value))
+	}
+	return GormClause{Query: strings.Join(exprs, " AND "), Args: args}, nil
+}
+
+// havingValue 数值按数字传参，避免与聚合结果按字符串比较
+func havingValue(value string) interface{} {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// dateTrunc 按方言生成时间截断表达式，周以周一为起始
+func dateTrunc(driver, unit, columnRef string) (string, error) {
+	if !dateTruncUnits[unit] {
+		return "", fmt.Errorf("不支持的时间粒度 %q", unit)
+	}
+	switch driver {
+	case Postgres:
+		return fmt.Sprintf("date_trunc('%s', %s)", unit, columnRef), nil
+	case Sqlite:
+		switch unit {
+		case "week":
+			return fmt.Sprintf("datetime(%s, 'start of day', '-' || ((strftime('%%w', %s) + 6) %% 7) || ' days')", columnRef, columnRef), nil
+		case "year":
+			return fmt.Sprintf("datetime(%s, 'start of year')", columnRef), nil
+		case "month":
+			return fmt.Sprintf("datetime(%s, 'start of month')", columnRef), nil
+		case "day":
+			return fmt.Sprintf("datetime(%s, 'start of day')", columnRef), nil
+		case "hour":
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:00:00', %s)", columnRef), nil
+		default:
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:00', %s)", columnRef), nil
+		}
+	default:
+		switch unit {
+		case "week":
+			return fmt.Sprintf("CAST(DATE_SUB(DATE(%s), INTERVAL WEEKDAY(%s) DAY) AS DATETIME)", columnRef, columnRef), nil
+		case "year":
+			return fmt.Sprintf("CAST(DATE_FORMAT(%s, '%%Y-01-01') AS DATETIME)", columnRef), nil
+		case "month":
+			return fmt.Sprintf("CAST(DATE_FORMAT(%s, '%%Y-%%m-01') AS DATETIME)", columnRef), nil
+		case "day":
+			return fmt.Sprintf("CAST(DATE(%s) AS DATETIME)", columnRef), nil
+		case "hour":
+			return fmt.Sprintf("CAST(DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:00:00') AS DATETIME)", columnRef), nil
+		default:
+			return fmt.Sprintf("CAST(DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:%%i:00') AS DATETIME)", columnRef), nil
+		}
+	}
+}
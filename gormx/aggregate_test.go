@@ -0,0 +1,67 @@
+package gormx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type aggOrder struct {
+	Id        int64
+	Status    int64
+	Amount    float64
+	CreateBy  string
+	CreatedAt time.Time
+}
+
+func (aggOrder) TableName() string {
+	return "order"
+}
+
+func TestAggregate(t *testing.T) {
+	db := newCursorDB(t)
+	assert.Nil(t, db.AutoMigrate(&aggOrder{}))
+	day := time.Date(2024, 3, 6, 10, 30, 0, 0, time.UTC)
+	assert.Nil(t, db.Create(&[]aggOrder{
+		{Status: 1, Amount: 10, CreateBy: "u1", CreatedAt: day},
+		{Status: 1, Amount: 20, CreateBy: "u1", CreatedAt: day.Add(time.Hour)},
+		{Status: 2, Amount: 5, CreateBy: "u2", CreatedAt: day},
+		{Status: 1, Amount: 1, CreateBy: "u1", CreatedAt: day.AddDate(0, 0, 1)},
+		{Status: 1, Amount: 100, CreateBy: "u2", CreatedAt: day},
+	}).Error)
+
+	type report struct {
+		Day    time.Time `group:"date_trunc:day;table:order;column:created_at"`
+		Status int64     `group:"column:status"`
+		Amount float64   `agg:"sum;column:amount" having:"gt:1"`
+		Count  int64     `agg:"count"`
+	}
+	type query struct {
+		Status []int64 `search:"type:in;table:order;column:status"`
+	}
+	list, err := Aggregate[report](db.Model(&aggOrder{}), query{Status: []int64{1}},
+		PermissionData("order", &DataPermission{DataScope: "5", UserId: "u1"}))
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "2024-03-06 00:00:00", list[0].Day.Format(time.DateTime))
+	assert.Equal(t, report{Day: list[0].Day, Status: 1, Amount: 30, Count: 2}, list[0])
+
+	stmt := newDryRunDB(t).Model(&aggOrder{}).Scopes(AggregateScope(report{})).Scan(&[]report{}).Statement
+	assert.Equal(t, "SELECT datetime(`order`.`created_at`, 'start of day') AS `day`, `status` AS `status`, "+
+		"SUM(`amount`) AS `amount`, COUNT(*) AS `count` FROM `order` "+
+		"GROUP BY datetime(`order`.`created_at`, 'start of day'),`status` HAVING SUM(`amount`) > ? "+
+		"ORDER BY datetime(`order`.`created_at`, 'start of day'),`status`", stmt.SQL.String())
+
+	expr, err := dateTrunc(Postgres, "week", `"created_at"`)
+	assert.Nil(t, err)
+	assert.Equal(t, `date_trunc('week', "created_at")`, expr)
+	_, err = dateTrunc(Mysql, "day'); DROP", "`created_at`")
+	assert.NotNil(t, err)
+
+	type bad struct {
+		Amount float64 `agg:"median;column:amount"`
+	}
+	err = newDryRunDB(t).Model(&aggOrder{}).Scopes(AggregateScope(&bad{})).Scan(&[]bad{}).Error
+	assert.NotNil(t, err)
+}
@@ -14,6 +14,9 @@ type BatchExecModel[DBModel any] interface {
 	ExecCtx(ctx context.Context, execCtx gormx.ExecCtxFn, keys ...string) error
 }
 
+// gormx.CachedModel 自动实现 BatchExecModel
+var _ BatchExecModel[struct{}] = (*gormx.CachedModel[struct{}])(nil)
+
 // BatchExecCtx 对批量数据执行操作，并自动处理缓存键
 // 参数说明：
 //
@@ -0,0 +1,176 @@
+package gormx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// notFoundPlaceholder go-zero 缓存中表示记录不存在的占位值
+	notFoundPlaceholder = "*"
+	// 与 go-zero 缓存的默认过期时间一致
+	cacheDefaultExpiry         = time.Hour * 24 * 7
+	cacheDefaultNotFoundExpiry = time.Minute
+)
+
+// cacheOptions 按 go-zero 的默认值解析缓存过期时间
+func cacheOptions(opts ...cache.Option) cache.Options {
+	var o cache.Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Expiry <= 0 {
+		o.Expiry = cacheDefaultExpiry
+	}
+	if o.NotFoundExpiry <= 0 {
+		o.NotFoundExpiry = cacheDefaultNotFoundExpiry
+	}
+	return o
+}
+
+// QueryRowsByPrimaryKeysCtx 按主键批量查询，结果与 ids 顺序一致，记录不存在时对应位置为 nil
+// 先批量读取缓存，未命中的主键通过一条 IN 查询加载并回写缓存，相同的未命中集合共享一次查询；
+// keyer 与 QueryRowIndexCtx 的主键缓存键一致，T 为 gorm 模型
+func QueryRowsByPrimaryKeysCtx[T any, K comparable](ctx context.Context, cc CachedConn,
+	keyer func(primary interface{}) string, ids []K) (rows []*T, err error) {
+	ctx, span := startSpan(ctx, "QueryRowsByPrimaryKeys")
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	rows = make([]*T, len(ids))
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = keyer(id)
+	}
+	hit, err := batchGetCache[T](ctx, cc, keys)
+	if err != nil {
+		return nil, err
+	}
+	// 未命中的主键去重后加载
+	missIds, missKeys := make([]K, 0, len(ids)), make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for i, key := range keys {
+		if v, ok := hit[key]; ok {
+			rows[i] = v
+		} else if !seen[key] {
+			seen[key] = true
+			missIds, missKeys = append(missIds, ids[i]), append(missKeys, key)
+		}
+	}
+	if len(missIds) == 0 {
+		return rows, nil
+	}
+	flightKey := append([]string(nil), missKeys...)
+	sort.Strings(flightKey)
+	val, err := singleFlights.Do("batch:"+strings.Join(flightKey, ","), func() (interface{}, error) {
+		return loadRowsByPrimaryKeys[T](ctx, cc, missKeys, missIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+	loaded := val.(map[string]T)
+	for i, key := range keys {
+		if !seen[key] {
+			continue
+		}
+		if row, ok := loaded[key]; ok {
+			rows[i] = &row
+		}
+	}
+	return rows, nil
+}
+
+// batchGetCache 批量读取缓存，返回命中的键，值为 nil 表示缓存了不存在占位
+// 单节点 Redis 使用 MGET，其他缓存逐个读取
+func batchGetCache[T any](ctx context.Context, cc CachedConn, keys []string) (map[string]*T, error) {
+	hit := make(map[string]*T, len(keys))
+	if cc.rds == nil {
+		for _, key := range keys {
+			var row T
+			err := cc.cache.GetCtx(ctx, key, &row)
+			if err == nil {
+				hit[key] = &row
+			} else if !cc.cache.IsNotFound(err) {
+				return nil, err
+			}
+		}
+		return hit, nil
+	}
+	vals, err := cc.rds.MgetCtx(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		switch v {
+		case "":
+		case notFoundPlaceholder:
+			hit[keys[i]] = nil
+		default:
+			var row T
+			if err = json.Unmarshal([]byte(v), &row); err != nil {
+				logx.WithContext(ctx).Errorf("unmarshal cache, key: %s, value: %s, error: %v", keys[i], v, err)
+				continue
+			}
+			hit[keys[i]] = &row
+		}
+	}
+	return hit, nil
+}
+
+// loadRowsByPrimaryKeys 以一条 IN 查询加载记录并回写缓存，返回以缓存键索引的记录
+func loadRowsByPrimaryKeys[T any, K comparable](ctx context.Context, cc CachedConn, keys []string, ids []K) (map[string]T, error) {
+	db := cc.reader(ctx)
+	sch, err := schema.Parse(new(T), schemaCache, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	pk := sch.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, errors.New("模型未定义主键: " + sch.Name)
+	}
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+	var list []T
+	err = db.Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Values: values}).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	byPk := make(map[string]T, len(list))
+	for i := range list {
+		v, _ := pk.ValueOf(ctx, reflect.ValueOf(&list[i]).Elem())
+		byPk[fmt.Sprint(v)] = list[i]
+	}
+	logger := logx.WithContext(ctx)
+	loaded := make(map[string]T, len(list))
+	for i, id := range ids {
+		row, ok := byPk[fmt.Sprint(id)]
+		if ok {
+			loaded[keys[i]] = row
+			err = cc.cache.SetWithExpireCtx(ctx, keys[i], row, cc.aroundDuration(cc.options.Expiry))
+		} else if cc.rds != nil {
+			seconds := int(math.Ceil(cc.aroundDuration(cc.options.NotFoundExpiry).Seconds()))
+			_, err = cc.rds.SetnxExCtx(ctx, keys[i], notFoundPlaceholder, seconds)
+		}
+		if err != nil {
+			logger.Errorf("回写缓存 %s 出错: %v", keys[i], err)
+			err = nil
+		}
+	}
+	return loaded, nil
+}
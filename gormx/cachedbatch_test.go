@@ -0,0 +1,43 @@
+package gormx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestQueryRowsByPrimaryKeysCtx(t *testing.T) {
+	ctx := context.Background()
+	db := newCursorDB(t)
+	mc := newMemCache()
+	cc := NewConnWithCache(db, mc)
+	keyer := func(primary interface{}) string {
+		return fmt.Sprintf("cache:user:id:%v", primary)
+	}
+	var queries int
+	assert.Nil(t, db.Callback().Query().After("gorm:query").Register("test:count", func(*gorm.DB) {
+		queries++
+	}))
+	assert.Nil(t, mc.Set(keyer(1), searchUser{Id: 1, Name: "cached"}))
+
+	rows, err := QueryRowsByPrimaryKeysCtx[searchUser](ctx, cc, keyer, []int64{3, 99, 1, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, queries)
+	assert.Len(t, rows, 4)
+	assert.Equal(t, int64(3), rows[0].Id)
+	assert.Nil(t, rows[1])
+	assert.Equal(t, "cached", rows[2].Name)
+	assert.Equal(t, int64(3), rows[3].Id)
+	assert.NotSame(t, rows[0], rows[3])
+	assert.True(t, mc.has(keyer(3)))
+
+	// 全部命中缓存时不查询数据库
+	rows, err = QueryRowsByPrimaryKeysCtx[searchUser](ctx, cc, keyer, []int64{1, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, queries)
+	assert.Equal(t, "cached", rows[0].Name)
+	assert.Equal(t, int64(3), rows[1].Id)
+}
@@ -52,17 +52,33 @@ type (
 		db                 *gorm.DB
 		cache              cache.Cache
 		unstableExpiryTime mathx.Unstable
+		delayDelete        time.Duration // 大于 0 时删除缓存后延迟再删除一次
+		rds                *redis.Redis  // 单节点 Redis，用于批量读取；为空时逐个读取
+		options            cache.Options // 缓存过期时间，批量查询回写时使用
+		replicas           *ReplicaSet   // 只读副本，为空时读写均使用 db
 	}
 
+	// Conn 不带缓存的连接，设置只读副本后查询分发到副本，写入与事务使用主库
 	Conn struct {
-		db *gorm.DB
+		db       *gorm.DB
+		replicas *ReplicaSet
 	}
 )
 
+// NewSqlConn 返回不带缓存的 Conn。
+func NewSqlConn(db *gorm.DB) Conn {
+	return Conn{db: db}
+}
+
 // NewConn 返回一个带有 Redis 集群缓存的 CachedConn。
 func NewConn(db *gorm.DB, c cache.CacheConf, opts ...cache.Option) CachedConn {
+	if len(c) == 1 && cache.TotalWeights(c) > 0 {
+		return NewNodeConn(db, redis.MustNewRedis(c[0].RedisConf), opts...)
+	}
 	cc := cache.New(c, singleFlights, stats, ErrNotFound, opts...)
-	return NewConnWithCache(db, cc)
+	conn := NewConnWithCache(db, cc)
+	conn.options = cacheOptions(opts...)
+	return conn
 }
 
 // NewConnWithCache 返回一个带有自定义缓存的 CachedConn。
@@ -71,23 +87,38 @@ func NewConnWithCache(db *gorm.DB, c cache.Cache) CachedConn {
 		db:                 db,
 		cache:              c,
 		unstableExpiryTime: mathx.NewUnstable(expiryDeviation),
+		options:            cacheOptions(),
 	}
 }
 
 // NewNodeConn 返回一个带有 Redis 单节点缓存的 CachedConn。
 func NewNodeConn(db *gorm.DB, rds *redis.Redis, opts ...cache.Option) CachedConn {
 	cc := cache.NewNode(rds, singleFlights, stats, ErrNotFound, opts...)
-	return NewConnWithCache(db, cc)
+	conn := NewConnWithCache(db, cc)
+	if rds.Type == redis.NodeType {
+		conn.rds = rds
+	}
+	conn.options = cacheOptions(opts...)
+	return conn
+}
+
+// WithReplicas 返回缓存未命中的查询分发到 replicas 的 CachedConn，写入与事务使用主库。
+// 副本存在复制延迟，未命中时可能回写旧值，建议同时设置 WithDelayDelete 且延迟大于复制延迟。
+// 副本启用主库已注册的插件，数据权限、租户等条件在副本上同样生效。
+func (cc CachedConn) WithReplicas(replicas *ReplicaSet) CachedConn {
+	replicas.usePlugins(cc.db)
+	cc.replicas = replicas
+	return cc
 }
 
 // DelCache 删除指定键的缓存。
 func (cc CachedConn) DelCache(keys ...string) error {
-	return cc.cache.DelCtx(context.Background(), keys...)
+	return cc.delCache(context.Background(), keys)
 }
 
-// DelCacheCtx 删除指定键的缓存。
+// DelCacheCtx 删除指定键的缓存，ctx 处于 TransactCtx 开启的事务中时在提交后删除。
 func (cc CachedConn) DelCacheCtx(ctx context.Context, keys ...string) error {
-	return cc.cache.DelCtx(ctx, keys...)
+	return cc.delCache(ctx, keys)
 }
 
 // GetCache 将给定键的缓存反序列化到 v 中。
@@ -106,8 +137,9 @@ func (cc CachedConn) Exec(exec ExecCtxFn, keys ...string) error {
 }
 
 // ExecCtx 在给定键上运行给定的 exec，并返回执行结果。
+// ctx 处于 TransactCtx 开启的事务中时使用该事务执行，缓存在提交后删除。
 func (cc CachedConn) ExecCtx(ctx context.Context, execCtx ExecCtxFn, keys ...string) error {
-	err := execCtx(cc.db.WithContext(ctx))
+	err := execCtx(cc.conn(ctx))
 	if err != nil {
 		return err
 	}
@@ -117,6 +149,13 @@ func (cc CachedConn) ExecCtx(ctx context.Context, execCtx ExecCtxFn, keys ...str
 	return nil
 }
 
+// WithReplicas 返回读请求分发到 replicas 的 Conn，副本启用主库已注册的插件。
+func (cc Conn) WithReplicas(replicas *ReplicaSet) Conn {
+	replicas.usePlugins(cc.db)
+	cc.replicas = replicas
+	return cc
+}
+
 // ExecNoCache 运行给定的 SQL 语句，不影响缓存。
 func (cc Conn) ExecNoCache(exec ExecCtxFn) error {
 	return cc.ExecNoCacheCtx(context.Background(), exec)
@@ -128,9 +167,30 @@ func (cc Conn) ExecNoCacheCtx(ctx context.Context, execCtx ExecCtxFn) (err error
 	defer func() {
 		endSpan(span, err)
 	}()
+	markWrite(ctx)
 	return execCtx(cc.db.WithContext(ctx))
 }
 
+// QueryNoCache 运行给定的查询。
+func (cc Conn) QueryNoCache(query QueryCtxFn) error {
+	return cc.QueryNoCacheCtx(context.Background(), query)
+}
+
+// QueryNoCacheCtx 运行给定的查询，设置了只读副本时使用副本。
+func (cc Conn) QueryNoCacheCtx(ctx context.Context, query QueryCtxFn) (err error) {
+	ctx, span := startSpan(ctx, "QueryNoCache")
+	defer func() {
+		endSpan(span, err)
+	}()
+	return query(cc.replicas.pick(ctx, cc.db).WithContext(ctx))
+}
+
+// TransactCtx 在主库的事务中运行给定的 fn。
+func (cc Conn) TransactCtx(ctx context.Context, fn func(db *gorm.DB) error, opts ...*sql.TxOptions) error {
+	markWrite(ctx)
+	return cc.db.WithContext(ctx).Transaction(fn, opts...)
+}
+
 // ExecNoCache 运行给定的 SQL 语句，不影响缓存。
 func (cc CachedConn) ExecNoCache(exec ExecCtxFn) error {
 	return cc.ExecNoCacheCtx(context.Background(), exec)
@@ -142,7 +202,7 @@ func (cc CachedConn) ExecNoCacheCtx(ctx context.Context, execCtx ExecCtxFn) (err
 	defer func() {
 		endSpan(span, err)
 	}()
-	return execCtx(cc.db.WithContext(ctx))
+	return execCtx(cc.conn(ctx))
 }
 
 // QueryRowIndex 将给定键的缓存反序列化到 v 中。
@@ -162,7 +222,7 @@ func (cc CachedConn) QueryRowIndexCtx(ctx context.Context, v interface{}, key st
 	var found bool
 
 	if err = cc.cache.TakeWithExpireCtx(ctx, &primaryKey, key, func(val interface{}, expire time.Duration) error {
-		primaryKey, err = indexQuery(cc.db.WithContext(ctx), v)
+		primaryKey, err = indexQuery(cc.reader(ctx), v)
 		if err != nil {
 			return err
 		}
@@ -175,7 +235,7 @@ func (cc CachedConn) QueryRowIndexCtx(ctx context.Context, v interface{}, key st
 		return nil
 	}
 	return cc.cache.TakeCtx(ctx, v, keyer(primaryKey), func(v interface{}) error {
-		return primaryQuery(cc.db.WithContext(ctx), v, primaryKey)
+		return primaryQuery(cc.reader(ctx), v, primaryKey)
 	})
 }
 
@@ -186,17 +246,21 @@ func (cc CachedConn) QueryCtx(ctx context.Context, v interface{}, key string, qu
 		endSpan(span, err)
 	}()
 	return cc.cache.TakeCtx(ctx, v, key, func(v interface{}) error {
-		return query(cc.db.WithContext(ctx))
+		return query(cc.reader(ctx))
 	})
 }
 
 // QueryNoCacheCtx 运行给定的查询，不影响缓存。
+// ctx 处于 TransactCtx 开启的事务中时使用该事务，否则设置了只读副本时使用副本。
 func (cc CachedConn) QueryNoCacheCtx(ctx context.Context, query QueryCtxFn) (err error) {
 	ctx, span := startSpan(ctx, "QueryNoCache")
 	defer func() {
 		endSpan(span, err)
 	}()
-	return query(cc.db.WithContext(ctx))
+	if t, ok := cacheTxFrom(ctx); ok && t.source == cc.db {
+		return query(t.db.WithContext(ctx))
+	}
+	return query(cc.reader(ctx))
 }
 
 // QueryWithExpireCtx 将给定键的缓存反序列化到 v 中，并设置过期时间和查询函数。
@@ -206,7 +270,7 @@ func (cc CachedConn) QueryWithExpireCtx(ctx context.Context, v interface{}, key
 		endSpan(span, err)
 	}()
 	err = cc.cache.TakeCtx(ctx, v, key, func(v interface{}) error {
-		return query(cc.db.WithContext(ctx))
+		return query(cc.reader(ctx))
 	})
 	if err != nil {
 		return err
@@ -221,7 +285,7 @@ func (cc CachedConn) QueryWithCallbackExpireCtx(ctx context.Context, v interface
 		endSpan(span, err)
 	}()
 	err = cc.cache.TakeCtx(ctx, v, key, func(v interface{}) error {
-		return query(cc.db.WithContext(ctx))
+		return query(cc.reader(ctx))
 	})
 	if err != nil {
 		return err
@@ -258,8 +322,28 @@ func (cc CachedConn) Transact(fn func(db *gorm.DB) error, opts ...*sql.TxOptions
 }
 
 // TransactCtx 在事务模式下运行给定的 fn。
+// fn 内以 db.Statement.Context 调用 ExecCtx、DelCacheCtx 时使用该事务执行，缓存键在提交后统一删除，回滚时丢弃；
+// 在事务内再次调用 TransactCtx 时使用保存点，成功后缓存键并入外层事务。
 func (cc CachedConn) TransactCtx(ctx context.Context, fn func(db *gorm.DB) error, opts ...*sql.TxOptions) error {
-	return cc.db.WithContext(ctx).Transaction(fn, opts...)
+	parent, nested := cacheTxFrom(ctx)
+	db := cc.db
+	if nested && parent.source == cc.db {
+		db = parent.db
+	}
+	markWrite(ctx)
+	t := &cacheTx{source: cc.db}
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		t.db = tx
+		return fn(tx.WithContext(context.WithValue(ctx, cacheTxKey{}, t)))
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	if nested && parent.source == cc.db {
+		parent.add(t.pending...)
+		return nil
+	}
+	return t.flush(ctx)
 }
 
 var sqlAttributeKey = attribute.Key("sql.method")
@@ -0,0 +1,131 @@
+package gormx
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/hash"
+)
+
+// ListCacheExpiry 列表缓存的过期时间，表版本更新后旧版本的列表缓存在此时间后自然过期
+const ListCacheExpiry = time.Hour
+
+// ExecWithTablesCtx 运行 exec 后删除 keys，并更新 tables 的版本使其列表缓存失效
+// ctx 处于 TransactCtx 开启的事务中时在提交后执行
+func (cc CachedConn) ExecWithTablesCtx(ctx context.Context, exec ExecCtxFn, tables []string, keys ...string) error {
+	if err := exec(cc.conn(ctx)); err != nil {
+		return err
+	}
+	return cc.delCache(ctx, keys, tables...)
+}
+
+// BumpTableVersionsCtx 更新表版本，使 QueryListCtx 缓存的相关列表失效
+func (cc CachedConn) BumpTableVersionsCtx(ctx context.Context, tables ...string) error {
+	return cc.delCache(ctx, nil, tables...)
+}
+
+// QueryListCtx 缓存列表、分页查询的结果，tables 为查询涉及的表，params 为查询及分页参数
+// 缓存键由表名、表版本及 params 的哈希组成，相关表的版本更新后自动失效，无需扫描删除
+func (cc CachedConn) QueryListCtx(ctx context.Context, v interface{}, tables []string, params interface{},
+	query QueryCtxFn) (err error) {
+	ctx, span := startSpan(ctx, "QueryList")
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	key, err := cc.listCacheKey(ctx, tables, params)
+	if err != nil {
+		return err
+	}
+	if err = cc.cache.GetCtx(ctx, key, v); err == nil || !cc.cache.IsNotFound(err) {
+		return err
+	}
+	val, err := singleFlights.Do(key, func() (interface{}, error) {
+		if err := query(cc.reader(ctx)); err != nil {
+			return nil, err
+		}
+		if err := cc.cache.SetWithExpireCtx(ctx, key, v, cc.aroundDuration(ListCacheExpiry)); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(val.([]byte), v)
+}
+
+// listCacheKey 生成列表缓存键，e.g. cache:list:menu,role:<md5>
+func (cc CachedConn) listCacheKey(ctx context.Context, tables []string, params interface{}) (string, error) {
+	tables = append([]string(nil), tables...)
+	sort.Strings(tables)
+	versions, err := cc.tableVersions(ctx, tables)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(struct {
+		Versions []int64
+		Params   interface{}
+	}{versions, params})
+	if err != nil {
+		return "", err
+	}
+	return "cache:list:" + strings.Join(tables, ",") + ":" + hash.Md5Hex(data), nil
+}
+
+func tableVersionKey(table string) string {
+	return "cache:version:" + table
+}
+
+// tableVersions 读取表版本，不存在时以当前时间初始化，避免与过期前的版本重复
+// 单节点 Redis 使用 SETNX、INCR 保证原子性，其他缓存通过 Get、Set 读写，并发初始化与更新时可能互相覆盖
+func (cc CachedConn) tableVersions(ctx context.Context, tables []string) ([]int64, error) {
+	versions := make([]int64, len(tables))
+	for i, table := range tables {
+		key := tableVersionKey(table)
+		if cc.rds == nil {
+			err := cc.cache.GetCtx(ctx, key, &versions[i])
+			if cc.cache.IsNotFound(err) {
+				versions[i] = time.Now().UnixNano()
+				err = cc.cache.SetCtx(ctx, key, versions[i])
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		val, err := cc.rds.GetCtx(ctx, key)
+		if err == nil && val == "" {
+			if _, err = cc.rds.SetnxCtx(ctx, key, strconv.FormatInt(time.Now().UnixNano(), 10)); err == nil {
+				val, err = cc.rds.GetCtx(ctx, key)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if versions[i], err = strconv.ParseInt(val, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
+
+// bumpTableVersions 更新表版本
+func (cc CachedConn) bumpTableVersions(ctx context.Context, tables []string) error {
+	for _, table := range tables {
+		var err error
+		if cc.rds != nil {
+			_, err = cc.rds.IncrCtx(ctx, tableVersionKey(table))
+		} else {
+			err = cc.cache.SetCtx(ctx, tableVersionKey(table), time.Now().UnixNano())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package gormx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestQueryListCtx(t *testing.T) {
+	ctx := context.Background()
+	db := newCursorDB(t)
+	cc := NewConnWithCache(db, newMemCache())
+	tables := []string{"user"}
+	var queries int
+	list := func(status int64) (users []searchUser) {
+		assert.Nil(t, cc.QueryListCtx(ctx, &users, tables, map[string]int64{"status": status, "page": 1}, func(conn *gorm.DB) error {
+			queries++
+			return conn.Where("status = ?", status).Order("id").Find(&users).Error
+		}))
+		return
+	}
+
+	assert.Equal(t, []int64{3, 6}, cursorIds(list(0)))
+	assert.Equal(t, []int64{3, 6}, cursorIds(list(0)))
+	assert.Equal(t, 1, queries)
+	assert.Equal(t, []int64{1, 4, 7}, cursorIds(list(1)))
+	assert.Equal(t, 2, queries)
+
+	// 事务提交后才更新表版本
+	err := cc.TransactCtx(ctx, func(tx *gorm.DB) error {
+		err := cc.ExecWithTablesCtx(tx.Statement.Context, func(conn *gorm.DB) error {
+			return conn.Create(&searchUser{Id: 9, Status: 0}).Error
+		}, tables)
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{3, 6}, cursorIds(list(0)))
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, queries)
+	assert.Equal(t, []int64{3, 6, 9}, cursorIds(list(0)))
+	assert.Equal(t, 3, queries)
+
+	assert.Nil(t, cc.BumpTableVersionsCtx(ctx, "user"))
+	list(1)
+	assert.Equal(t, 4, queries)
+}
@@ -0,0 +1,208 @@
+package gormx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type (
+	// CachedModel 带缓存的通用模型，按 gorm schema 的主键和唯一索引生成缓存键
+	// 主键缓存键为 cache:<表名>:<主键列>:<值>，唯一索引缓存键为 cache:<表名>:<列1>:<列2>:<值1>:<值2>；
+	// 写入时删除新旧数据的全部缓存键并更新表版本，同时实现 batchx.BatchExecModel
+	CachedModel[T any] struct {
+		CachedConn
+		table   string
+		pk      *schema.Field
+		uniques []cacheIndex
+	}
+
+	// cacheIndex 唯一索引及其列
+	cacheIndex struct {
+		name   string
+		fields []*schema.Field
+	}
+)
+
+// NewCachedModel 创建带缓存的通用模型，T 须定义主键
+func NewCachedModel[T any](conn CachedConn) (*CachedModel[T], error) {
+	sch, err := schema.Parse(new(T), schemaCache, conn.db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	if sch.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("模型 %s 未定义主键", sch.Name)
+	}
+	m := &CachedModel[T]{CachedConn: conn, table: sch.Table, pk: sch.PrioritizedPrimaryField}
+	seen := map[string]bool{}
+	add := func(ci cacheIndex) {
+		if k := ci.key("", nil); !seen[k] {
+			seen[k] = true
+			m.uniques = append(m.uniques, ci)
+		}
+	}
+	for name, idx := range sch.ParseIndexes() {
+		if idx.Class != "UNIQUE" {
+			continue
+		}
+		ci := cacheIndex{name: name}
+		for _, opt := range idx.Fields {
+			ci.fields = append(ci.fields, opt.Field)
+		}
+		add(ci)
+	}
+	for _, f := range sch.Fields {
+		if f.Unique && !f.PrimaryKey {
+			add(cacheIndex{name: f.DBName, fields: []*schema.Field{f}})
+		}
+	}
+	sort.Slice(m.uniques, func(i, j int) bool { return m.uniques[i].name < m.uniques[j].name })
+	return m, nil
+}
+
+// FindOne 按主键查询，不存在时返回 ErrNotFound
+func (m *CachedModel[T]) FindOne(ctx context.Context, primary interface{}) (*T, error) {
+	var resp T
+	err := m.QueryCtx(ctx, &resp, m.primaryKey(primary), func(conn *gorm.DB) error {
+		return conn.Where(m.pkExpr(primary)).First(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// FindOneBy 按唯一索引查询，unique 为索引名或唯一列的字段名、列名，values 与索引列顺序一致
+func (m *CachedModel[T]) FindOneBy(ctx context.Context, unique string, values ...interface{}) (*T, error) {
+	idx, ok := m.lookupIndex(unique)
+	if !ok {
+		return nil, errx.NewErrorf(errx.RequestParamError, "模型 %s 没有唯一索引 %s", m.table, unique)
+	}
+	if len(values) != len(idx.fields) {
+		return nil, errx.NewErrorf(errx.RequestParamError, "唯一索引 %s 需要 %d 个值", unique, len(idx.fields))
+	}
+	var resp T
+	err := m.QueryRowIndexCtx(ctx, &resp, idx.key(m.table, values), m.primaryKey,
+		func(conn *gorm.DB, v interface{}) (interface{}, error) {
+			exprs := make([]clause.Expression, len(idx.fields))
+			for i, f := range idx.fields {
+				exprs[i] = clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: values[i]}
+			}
+			if err := conn.Where(clause.And(exprs...)).First(v).Error; err != nil {
+				return nil, err
+			}
+			pk, _ := m.pk.ValueOf(ctx, reflect.ValueOf(v).Elem())
+			return pk, nil
+		}, func(conn *gorm.DB, v, primary interface{}) error {
+			return conn.Where(m.pkExpr(primary)).First(v).Error
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Insert 新增记录，删除新记录的缓存键以清除不存在占位
+func (m *CachedModel[T]) Insert(ctx context.Context, data *T) error {
+	if err := m.conn(ctx).Create(data).Error; err != nil {
+		return err
+	}
+	return m.delCache(ctx, m.GetCacheKeys(data), m.table)
+}
+
+// Update 按主键保存记录，删除新旧记录的缓存键
+func (m *CachedModel[T]) Update(ctx context.Context, data *T) error {
+	primary, _ := m.pk.ValueOf(ctx, reflect.ValueOf(data).Elem())
+	old, err := m.findOld(ctx, primary)
+	if err != nil {
+		return err
+	}
+	if err = m.conn(ctx).Save(data).Error; err != nil {
+		return err
+	}
+	return m.delCache(ctx, append(m.GetCacheKeys(old), m.GetCacheKeys(data)...), m.table)
+}
+
+// Delete 按主键删除记录，删除旧记录的缓存键；记录不存在时返回 ErrNotFound
+func (m *CachedModel[T]) Delete(ctx context.Context, primary interface{}) error {
+	old, err := m.findOld(ctx, primary)
+	if err != nil {
+		return err
+	}
+	if err = m.conn(ctx).Where(m.pkExpr(primary)).Delete(new(T)).Error; err != nil {
+		return err
+	}
+	return m.delCache(ctx, m.GetCacheKeys(old), m.table)
+}
+
+// ExecCtx 运行 exec 后删除 keys 并更新表版本，用于 batchx.BatchExecCtx 等批量写入
+func (m *CachedModel[T]) ExecCtx(ctx context.Context, exec ExecCtxFn, keys ...string) error {
+	return m.ExecWithTablesCtx(ctx, exec, []string{m.table}, keys...)
+}
+
+// GetCacheKeys 记录的主键及全部唯一索引缓存键
+func (m *CachedModel[T]) GetCacheKeys(data *T) []string {
+	if data == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(data).Elem()
+	primary, _ := m.pk.ValueOf(context.Background(), rv)
+	keys := []string{m.primaryKey(primary)}
+	for _, idx := range m.uniques {
+		values := make([]interface{}, len(idx.fields))
+		for i, f := range idx.fields {
+			values[i], _ = f.ValueOf(context.Background(), rv)
+		}
+		keys = append(keys, idx.key(m.table, values))
+	}
+	return keys
+}
+
+// findOld 不经缓存读取当前记录，事务中使用事务句柄
+func (m *CachedModel[T]) findOld(ctx context.Context, primary interface{}) (*T, error) {
+	var old T
+	if err := m.conn(ctx).Where(m.pkExpr(primary)).First(&old).Error; err != nil {
+		return nil, err
+	}
+	return &old, nil
+}
+
+func (m *CachedModel[T]) primaryKey(primary interface{}) string {
+	return fmt.Sprintf("cache:%s:%s:%v", m.table, m.pk.DBName, primary)
+}
+
+func (m *CachedModel[T]) pkExpr(primary interface{}) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: m.pk.DBName}, Value: primary}
+}
+
+// lookupIndex 按索引名、字段名或列名查找唯一索引
+func (m *CachedModel[T]) lookupIndex(name string) (cacheIndex, bool) {
+	for _, idx := range m.uniques {
+		if idx.name == name || len(idx.fields) == 1 && (idx.fields[0].Name == name || idx.fields[0].DBName == name) {
+			return idx, true
+		}
+	}
+	return cacheIndex{}, false
+}
+
+func (idx cacheIndex) key(table string, values []interface{}) string {
+	var b strings.Builder
+	b.WriteString("cache:")
+	b.WriteString(table)
+	for _, f := range idx.fields {
+		b.WriteByte(':')
+		b.WriteString(f.DBName)
+	}
+	for _, v := range values {
+		b.WriteByte(':')
+		b.WriteString(fmt.Sprint(v))
+	}
+	return b.String()
+}
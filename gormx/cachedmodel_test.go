@@ -0,0 +1,59 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cachedAccount struct {
+	Id     int64  `gorm:"primaryKey"`
+	Name   string `gorm:"uniqueIndex"`
+	Tenant int64  `gorm:"uniqueIndex:idx_tenant_code"`
+	Code   string `gorm:"uniqueIndex:idx_tenant_code"`
+}
+
+func (cachedAccount) TableName() string { return "account" }
+
+func TestCachedModel(t *testing.T) {
+	ctx := context.Background()
+	db := newCursorDB(t)
+	assert.Nil(t, db.AutoMigrate(&cachedAccount{}))
+	mc := newMemCache()
+	m, err := NewCachedModel[cachedAccount](NewConnWithCache(db, mc))
+	assert.Nil(t, err)
+
+	a := &cachedAccount{Name: "alice", Tenant: 1, Code: "a"}
+	assert.Nil(t, m.Insert(ctx, a))
+	assert.Equal(t, []string{"cache:account:id:1", "cache:account:name:alice", "cache:account:tenant:code:1:a"},
+		m.GetCacheKeys(a))
+
+	got, err := m.FindOneBy(ctx, "Name", "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), got.Id)
+	assert.True(t, mc.has("cache:account:name:alice"))
+	assert.True(t, mc.has("cache:account:id:1"))
+	got, err = m.FindOneBy(ctx, "idx_tenant_code", 1, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", got.Name)
+
+	// 更新后新旧唯一键均失效
+	a.Name = "bob"
+	assert.Nil(t, m.Update(ctx, a))
+	assert.False(t, mc.has("cache:account:name:alice"))
+	assert.False(t, mc.has("cache:account:id:1"))
+	got, err = m.FindOne(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", got.Name)
+	_, err = m.FindOneBy(ctx, "name", "alice")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	assert.Nil(t, m.Delete(ctx, 1))
+	_, err = m.FindOne(ctx, 1)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.True(t, errors.Is(m.Delete(ctx, 1), ErrNotFound))
+	_, err = m.FindOneBy(ctx, "code", "a")
+	assert.NotNil(t, err)
+}
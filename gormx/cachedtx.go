@@ -0,0 +1,153 @@
+package gormx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+// cacheFlushCallbackName gorm 默认事务提交后删除缓存的回调
+const cacheFlushCallbackName = "gorm-zero-cache-flush"
+
+type (
+	// cacheTxKey 上下文中当前事务的键
+	cacheTxKey struct{}
+
+	// cacheTx TransactCtx 开启的事务，收集事务内待删除的缓存键，提交后统一删除
+	cacheTx struct {
+		source  *gorm.DB // 开启事务的 CachedConn.db，同一连接的嵌套事务使用保存点
+		db      *gorm.DB // 事务句柄
+		mu      sync.Mutex
+		pending []pendingDel
+	}
+
+	// pendingDel 待删除的缓存键及待更新版本的表
+	pendingDel struct {
+		conn   CachedConn
+		keys   []string
+		tables []string
+	}
+)
+
+// WithDelayDelete 返回删除缓存后间隔 delay 再删除一次的 CachedConn，
+// 用于消除删除缓存与数据库提交之间并发读取写回旧值的窗口（延迟双删）
+func (cc CachedConn) WithDelayDelete(delay time.Duration) CachedConn {
+	cc.delayDelete = delay
+	return cc
+}
+
+// conn 返回写连接：当前上下文中同一连接的事务句柄，不在事务中时返回主库 cc.db
+func (cc CachedConn) conn(ctx context.Context) *gorm.DB {
+	markWrite(ctx)
+	if t, ok := cacheTxFrom(ctx); ok && t.source == cc.db {
+		return t.db.WithContext(ctx)
+	}
+	return cc.db.WithContext(ctx)
+}
+
+// reader 返回缓存未命中时的读连接，设置了只读副本时按 ReplicaSet 选择，事务中或读写一致窗口内使用主库
+func (cc CachedConn) reader(ctx context.Context) *gorm.DB {
+	return cc.replicas.pick(ctx, cc.db).WithContext(ctx)
+}
+
+// delCache 删除缓存并更新表版本，在事务中时记录到事务，提交后再执行
+func (cc CachedConn) delCache(ctx context.Context, keys []string, tables ...string) error {
+	if len(keys) == 0 && len(tables) == 0 {
+		return nil
+	}
+	p := pendingDel{conn: cc, keys: keys, tables: tables}
+	if t, ok := cacheTxFrom(ctx); ok {
+		t.add(p)
+		return nil
+	}
+	return p.do(ctx)
+}
+
+// registerCacheFlush 注册 gorm 默认事务提交后删除缓存的回调，已注册时跳过
+func registerCacheFlush(db *gorm.DB) error {
+	if db.Callback().Create().Get(cacheFlushCallbackName) != nil {
+		return nil
+	}
+	commit := "gorm:commit_or_rollback_transaction"
+	if err := db.Callback().Create().After(commit).Register(cacheFlushCallbackName, flushStatementCache); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After(commit).Register(cacheFlushCallbackName, flushStatementCache); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After(commit).Register(cacheFlushCallbackName, flushStatementCache)
+}
+
+// hookContext 返回 gorm 钩子中删除缓存使用的上下文：不在 TransactCtx 事务中时，
+// 待删除的缓存记录到当前语句，由 flushStatementCache 在 gorm 默认事务提交后删除；未注册回调时原样返回
+func hookContext(hook *gorm.DB) context.Context {
+	ctx := hook.Statement.Context
+	if _, ok := cacheTxFrom(ctx); ok || hook.Callback().Create().Get(cacheFlushCallbackName) == nil {
+		return ctx
+	}
+	t, _ := hook.Statement.Settings.LoadOrStore(cacheFlushCallbackName, &cacheTx{})
+	return context.WithValue(ctx, cacheTxKey{}, t)
+}
+
+// flushStatementCache 语句提交后删除钩子中记录的缓存，回滚时丢弃
+func flushStatementCache(db *gorm.DB) {
+	v, ok := db.Statement.Settings.LoadAndDelete(cacheFlushCallbackName)
+	if !ok || db.Error != nil {
+		return
+	}
+	ctx := db.Statement.Context
+	if err := v.(*cacheTx).flush(ctx); err != nil {
+		logx.WithContext(ctx).Errorf("提交后删除缓存出错: %v", err)
+	}
+}
+
+func cacheTxFrom(ctx context.Context) (*cacheTx, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	t, ok := ctx.Value(cacheTxKey{}).(*cacheTx)
+	return t, ok
+}
+
+func (t *cacheTx) add(p ...pendingDel) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, p...)
+}
+
+// flush 事务提交后删除收集的缓存键
+func (t *cacheTx) flush(ctx context.Context) error {
+	var err error
+	for _, p := range t.pending {
+		if e := p.do(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// do 删除缓存并更新表版本，设置了延迟时在 delay 后再删除一次
+func (p pendingDel) do(ctx context.Context) error {
+	var err error
+	if len(p.tables) > 0 {
+		err = p.conn.bumpTableVersions(ctx, p.tables)
+	}
+	if len(p.keys) == 0 {
+		return err
+	}
+	if e := p.conn.cache.DelCtx(ctx, p.keys...); e != nil && err == nil {
+		err = e
+	}
+	if delay := p.conn.delayDelete; delay > 0 {
+		ctx = context.WithoutCancel(ctx)
+		time.AfterFunc(delay, func() {
+			if err := p.conn.cache.DelCtx(ctx, p.keys...); err != nil {
+				logx.WithContext(ctx).Errorf("延迟删除缓存 %v 出错: %v", p.keys, err)
+			}
+		})
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTransactCtx(t *testing.T) {
+	ctx := context.Background()
+	db := newCursorDB(t)
+	mc := newMemCache()
+	cc := NewConnWithCache(db, mc)
+	for _, key := range []string{"a", "b", "c"} {
+		assert.Nil(t, mc.Set(key, 1))
+	}
+	insert := func(id int64) ExecCtxFn {
+		return func(conn *gorm.DB) error {
+			return conn.Create(&searchUser{Id: id, Name: "tx"}).Error
+		}
+	}
+	count := func() (n int64) {
+		assert.Nil(t, db.Model(&searchUser{}).Where("name = ?", "tx").Count(&n).Error)
+		return
+	}
+
+	// 回滚时丢弃缓存键
+	rollback := errors.New("rollback")
+	err := cc.TransactCtx(ctx, func(tx *gorm.DB) error {
+		assert.Nil(t, cc.ExecCtx(tx.Statement.Context, insert(10), "a"))
+		return rollback
+	})
+	assert.Equal(t, rollback, err)
+	assert.True(t, mc.has("a"))
+	assert.Equal(t, int64(0), count())
+
+	// 提交后删除，嵌套事务回滚的缓存键不删除
+	err = cc.TransactCtx(ctx, func(tx *gorm.DB) error {
+		assert.Nil(t, cc.ExecCtx(tx.Statement.Context, insert(10), "a"))
+		assert.True(t, mc.has("a"))
+		err := cc.TransactCtx(tx.Statement.Context, func(tx *gorm.DB) error {
+			assert.Nil(t, cc.ExecCtx(tx.Statement.Context, insert(11), "b"))
+			return rollback
+		})
+		assert.Equal(t, rollback, err)
+		return cc.TransactCtx(tx.Statement.Context, func(tx *gorm.DB) error {
+			return cc.ExecCtx(tx.Statement.Context, insert(12), "c")
+		})
+	})
+	assert.Nil(t, err)
+	assert.False(t, mc.has("a"))
+	assert.True(t, mc.has("b"))
+	assert.False(t, mc.has("c"))
+	assert.Equal(t, int64(2), count())
+
+	// 延迟双删
+	cc = cc.WithDelayDelete(10 * time.Millisecond)
+	err = cc.TransactCtx(ctx, func(tx *gorm.DB) error {
+		return cc.DelCacheCtx(tx.Statement.Context, "b")
+	})
+	assert.Nil(t, err)
+	assert.False(t, mc.has("b"))
+	assert.Nil(t, mc.Set("b", 2))
+	assert.Eventually(t, func() bool { return !mc.has("b") }, time.Second, 5*time.Millisecond)
+}
@@ -1,6 +1,6 @@
 package gormx
 
-import "strings"
+import "github.com/qiaogw/sub-sdk/searchtag"
 
 type Condition interface {
 	SetWhere(k string, v []interface{})
@@ -14,34 +14,40 @@ type GormCondition struct {
 	Join []*GormJoin
 }
 
+// GormClause 一条带参数的查询条件
+type GormClause struct {
+	Query string
+	Args  []interface{}
+}
+
+// GormPublic 按解析顺序保存条件，保证生成的 SQL 稳定
 type GormPublic struct {
-	Where map[string][]interface{}
+	Where []GormClause
 	Order []string
-	Or    map[string][]interface{}
+	Or    []GormClause
 }
 
 type GormJoin struct {
 	Type   string
 	JoinOn string
 	GormPublic
+	root *GormCondition
 }
 
+// SetJoinOn 关联子结构体中的嵌套关联，JOIN 语句按出现顺序追加到主条件
 func (e *GormJoin) SetJoinOn(t, on string) Condition {
-	return nil
+	if e.root == nil {
+		return nil
+	}
+	return e.root.SetJoinOn(t, on)
 }
 
 func (e *GormPublic) SetWhere(k string, v []interface{}) {
-	if e.Where == nil {
-		e.Where = make(map[string][]interface{})
-	}
-	e.Where[k] = v
+	e.Where = append(e.Where, GormClause{Query: k, Args: v})
 }
 
 func (e *GormPublic) SetOr(k string, v []interface{}) {
-	if e.Or == nil {
-		e.Or = make(map[string][]interface{})
-	}
-	e.Or[k] = v
+	e.Or = append(e.Or, GormClause{Query: k, Args: v})
 }
 
 func (e *GormPublic) SetOrder(k string) {
@@ -59,51 +65,16 @@ func (e *GormCondition) SetJoinOn(t, on string) Condition {
 		Type:       t,
 		JoinOn:     on,
 		GormPublic: GormPublic{},
+		root:       e,
 	}
 	e.Join = append(e.Join, join)
 	return join
 }
 
-type resolveSearchTag struct {
-	Type   string
-	Column string
-	Table  string
-	On     []string
-	Join   string
-}
+// resolveSearchTag 解析后的 search 标签
+type resolveSearchTag = searchtag.Tag
 
 // makeTag 解析search的tag标签
 func makeTag(tag string) *resolveSearchTag {
-	r := &resolveSearchTag{}
-	tags := strings.Split(tag, ";")
-	var ts []string
-	for _, t := range tags {
-		ts = strings.Split(t, ":")
-		if len(ts) == 0 {
-			continue
-		}
-		switch ts[0] {
-		case "type":
-			if len(ts) > 1 {
-				r.Type = ts[1]
-			}
-		case "column":
-			if len(ts) > 1 {
-				r.Column = ts[1]
-			}
-		case "table":
-			if len(ts) > 1 {
-				r.Table = ts[1]
-			}
-		case "on":
-			if len(ts) > 1 {
-				r.On = ts[1:]
-			}
-		case "join":
-			if len(ts) > 1 {
-				r.Join = ts[1]
-			}
-		}
-	}
-	return r
+	return searchtag.Parse(tag)
 }
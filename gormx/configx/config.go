@@ -39,6 +39,24 @@ type DbConf struct {
 	SlowThreshold int64  `json:",default=1000"`                                               // 慢查询阈值（单位：毫秒）
 	Schema        string `json:",default=public"`
 	TablePrefix   string `json:",optional"` // 表前缀 'it_'
+
+	SingularTable   bool          `json:",optional"` // 是否使用单数表名，sqlite 始终使用单数表名
+	ConnMaxLifetime time.Duration `json:",optional"` // 连接最长复用时间，0 表示不限制
+	ConnMaxIdleTime time.Duration `json:",optional"` // 连接最长空闲时间，0 表示不限制
+	Plugins         []string      `json:",optional"` // 启用的插件名，见 plugins.Register，为空时启用默认插件
+
+	Replicas       []ReplicaConf `json:",optional"`                                           // 只读副本，读请求分发到健康的副本
+	ReplicaPolicy  string        `json:",default=roundrobin,options=roundrobin|leastlatency"` // 副本选择策略：轮询或最低延迟
+	ReadYourWrites int64         `json:",default=1000"`                                       // 写入后读取固定到主库的时间窗口（单位：毫秒）
+	HealthCheck    int64         `json:",default=5000"`                                       // 副本健康检查间隔（单位：毫秒）
+}
+
+// ReplicaConf 只读副本的连接信息，未配置的字段沿用主库配置
+type ReplicaConf struct {
+	Host     string // 服务器地址
+	Port     int    `json:",optional"` // 数据库端口
+	Username string `json:",optional"` // 数据库用户名
+	Password string `json:",optional"` // 数据库密码
 }
 
 // GormLogConfigI 定义了获取 Gorm 日志配置参数的接口
@@ -53,105 +71,49 @@ type GormLogConfigI interface {
 	ConnectWithConfig(cfg *gorm.Config) (*gorm.DB, error)
 }
 
+// GetConnect 按配置连接数据库，同 Open
 func GetConnect(conf DbConf) (*gorm.DB, error) {
-	switch conf.Driver {
-	case string(MySQL):
-		// 根据 DbConf 手动构造 Mysql 结构体（字段赋值可以直接赋值）
-		m := Mysql{
-			Driver:        conf.Driver,
-			Host:          conf.Host,
-			Port:          conf.Port,
-			Dbname:        conf.Dbname,
-			Username:      conf.Username,
-			Password:      conf.Password,
-			Config:        conf.Config,
-			MaxIdleConns:  conf.MaxIdleConns,
-			MaxOpenConns:  conf.MaxOpenConns,
-			LogMode:       conf.LogMode,
-			LogColorful:   conf.LogColorful,
-			SlowThreshold: conf.SlowThreshold,
-		}
-		return m.Connect()
-	case string(Postgres):
-		p := PgSql{
-			Driver:        conf.Driver,
-			Host:          conf.Host,
-			Port:          conf.Port,
-			Dbname:        conf.Dbname,
-			Username:      conf.Username,
-			Password:      conf.Password,
-			TimeZone:      conf.TimeZone,
-			SslMode:       conf.SslMode,
-			MaxIdleConns:  conf.MaxIdleConns,
-			MaxOpenConns:  conf.MaxOpenConns,
-			LogMode:       conf.LogMode,
-			LogColorful:   conf.LogColorful,
-			SlowThreshold: conf.SlowThreshold,
-		}
-		return p.Connect()
-	case string(Sqlite):
-		s := Sqlite3{
-			Driver:   conf.Driver,
-			Host:     conf.Host,
-			Dbname:   conf.Dbname,
-			Username: conf.Username,
-			Password: conf.Password,
-		}
-		return s.Connect()
-	default:
-		return nil, fmt.Errorf("只支持 %v,不支持的数据库驱动：%s", AllDBTypes, conf.Driver)
-	}
+	return Open(conf)
 }
 
+// GetConnectWithConfig 以自定义的 gorm.Config 连接数据库，同 Open(conf, WithGormConfig(cfg))
 func GetConnectWithConfig(conf DbConf, cfg *gorm.Config) (*gorm.DB, error) {
-	switch conf.Driver {
-	case string(MySQL):
-		// 根据 DbConf 手动构造 Mysql 结构体（字段赋值可以直接赋值）
-		m := Mysql{
-			Driver:        conf.Driver,
-			Host:          conf.Host,
-			Port:          conf.Port,
-			Dbname:        conf.Dbname,
-			Username:      conf.Username,
-			Password:      conf.Password,
-			Config:        conf.Config,
-			MaxIdleConns:  conf.MaxIdleConns,
-			MaxOpenConns:  conf.MaxOpenConns,
-			LogMode:       conf.LogMode,
-			LogColorful:   conf.LogColorful,
-			SlowThreshold: conf.SlowThreshold,
-		}
-		return m.ConnectWithConfig(cfg)
-	case string(Postgres):
-		p := PgSql{
-			Driver:        conf.Driver,
-			Host:          conf.Host,
-			Port:          conf.Port,
-			Dbname:        conf.Dbname,
-			Username:      conf.Username,
-			Password:      conf.Password,
-			TimeZone:      conf.TimeZone,
-			SslMode:       conf.SslMode,
-			MaxIdleConns:  conf.MaxIdleConns,
-			MaxOpenConns:  conf.MaxOpenConns,
-			LogMode:       conf.LogMode,
-			LogColorful:   conf.LogColorful,
-			SlowThreshold: conf.SlowThreshold,
-			Schema:        conf.Schema,
-		}
-		return p.ConnectWithConfig(cfg)
-	case string(Sqlite):
-		s := Sqlite3{
-			Driver:   conf.Driver,
-			Host:     conf.Host,
-			Dbname:   conf.Dbname,
-			Username: conf.Username,
-			Password: conf.Password,
+	return Open(conf, WithGormConfig(cfg))
+}
+
+// GetReplicaConnects 按 conf.Replicas 连接只读副本，opts 同 Open
+func GetReplicaConnects(conf DbConf, opts ...Option) ([]*gorm.DB, error) {
+	dbs := make([]*gorm.DB, 0, len(conf.Replicas))
+	for _, r := range conf.Replicas {
+		rc := conf.replica(r)
+		db, err := Open(rc, opts...)
+		if err != nil {
+			for _, opened := range dbs {
+				if sqlDB, e := opened.DB(); e == nil {
+					_ = sqlDB.Close()
+				}
+			}
+			return nil, fmt.Errorf("连接只读副本 %s:%d 出错: %w", rc.Host, rc.Port, err)
 		}
-		return s.ConnectWithConfig(cfg)
-	default:
-		return nil, fmt.Errorf("只支持 %v,不支持的数据库驱动：%s", AllDBTypes, conf.Driver)
+		dbs = append(dbs, db)
+	}
+	return dbs, nil
+}
+
+// replica 以副本的连接信息覆盖主库配置
+func (conf DbConf) replica(r ReplicaConf) DbConf {
+	conf.Replicas = nil
+	conf.Host = r.Host
+	if r.Port > 0 {
+		conf.Port = r.Port
+	}
+	if r.Username != "" {
+		conf.Username = r.Username
+	}
+	if r.Password != "" {
+		conf.Password = r.Password
 	}
+	return conf
 }
 
 // NewDefaultZeroLogger 根据配置创建一个默认的 ZeroLog 日志实例
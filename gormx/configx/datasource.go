@@ -0,0 +1,370 @@
+package configx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"gorm.io/gorm"
+)
+
+// ErrDataSourceNotFound 数据源未注册
+var ErrDataSourceNotFound = errors.New("数据源未注册")
+
+// retiredTTL idleTTL 为 0 时，配置变化或移除后的旧连接池在最后一次使用后保留的时间
+const retiredTTL = time.Minute
+
+// datasourceCallbackName 记录连接池最后使用时间的回调名
+const datasourceCallbackName = "gorm-zero-datasource:touch"
+
+type (
+	// DataSourceManager 按名称管理多个数据源，首次使用时通过 Open 连接并复用连接池，
+	// 空闲超过 idleTTL 的连接池自动关闭，配置变化时重新连接；
+	// 配置变化或移除后的旧连接池不立即关闭，待 Acquire 取出的连接全部释放且空闲后关闭
+	DataSourceManager struct {
+		mu      sync.Mutex
+		sources map[string]*dataSource
+		retired []*pool
+		idleTTL time.Duration
+		opts    []Option
+		start   sync.Once
+		done    chan struct{}
+		closed  bool
+	}
+
+	// DataSourceStats 数据源状态，未连接时 DBStats 为零值
+	DataSourceStats struct {
+		Name      string
+		Driver    string
+		Host      string
+		Dbname    string
+		Connected bool
+		LastUsed  time.Time
+		sql.DBStats
+	}
+
+	dataSource struct {
+		mu       sync.Mutex
+		conf     DbConf
+		pool     *pool
+		lastUsed time.Time
+	}
+
+	// pool 连接池，refs 为 Acquire 取出未释放的数量，lastUsed 为最后使用时间（UnixNano）
+	pool struct {
+		name     string
+		db       *gorm.DB
+		refs     atomic.Int64
+		lastUsed atomic.Int64
+	}
+)
+
+// NewDataSourceManager 创建数据源管理器，idleTTL 为 0 时不关闭空闲连接池，opts 同 Open
+func NewDataSourceManager(idleTTL time.Duration, opts ...Option) *DataSourceManager {
+	return &DataSourceManager{
+		sources: map[string]*dataSource{},
+		idleTTL: idleTTL,
+		opts:    opts,
+		done:    make(chan struct{}),
+	}
+}
+
+// Set 注册或更新数据源配置，配置变化时停用已有连接池，下次使用时按新配置连接
+func (m *DataSourceManager) Set(name string, conf DbConf) {
+	m.mu.Lock()
+	ds, ok := m.sources[name]
+	if !ok {
+		m.sources[name] = &dataSource{conf: conf}
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if reflect.DeepEqual(ds.conf, conf) {
+		return
+	}
+	ds.conf = conf
+	m.retire(ds.pool)
+	ds.pool = nil
+}
+
+// Get 返回数据源的连接，未连接时按注册的配置连接；
+// 连接池在空闲超过 idleTTL 后可能关闭，长时间使用时改用 Acquire
+func (m *DataSourceManager) Get(name string) (*gorm.DB, error) {
+	p, err := m.acquire(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.db, nil
+}
+
+// Acquire 取出数据源的连接，使用完后调用 release 释放，释放前连接池不会被关闭
+func (m *DataSourceManager) Acquire(name string) (db *gorm.DB, release func(), err error) {
+	p, err := m.acquire(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.refs.Add(1)
+	var once sync.Once
+	return p.db, func() {
+		once.Do(func() {
+			p.touch()
+			p.refs.Add(-1)
+		})
+	}, nil
+}
+
+// Open 以 conf 更新数据源配置后返回其连接，用于配置保存在数据库等外部存储中的数据源
+func (m *DataSourceManager) Open(name string, conf DbConf) (*gorm.DB, error) {
+	m.Set(name, conf)
+	return m.Get(name)
+}
+
+// OpenAcquire 以 conf 更新数据源配置后取出其连接，使用完后调用 release 释放
+func (m *DataSourceManager) OpenAcquire(name string, conf DbConf) (*gorm.DB, func(), error) {
+	m.Set(name, conf)
+	return m.Acquire(name)
+}
+
+// Remove 移除数据源，连接池待使用结束后关闭
+func (m *DataSourceManager) Remove(name string) {
+	m.mu.Lock()
+	ds, ok := m.sources[name]
+	delete(m.sources, name)
+	m.mu.Unlock()
+	if ok {
+		ds.mu.Lock()
+		m.retire(ds.pool)
+		ds.pool = nil
+		ds.mu.Unlock()
+	}
+}
+
+// Stats 返回全部数据源的状态，按名称排序
+func (m *DataSourceManager) Stats() []DataSourceStats {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.sources))
+	sources := make(map[string]*dataSource, len(m.sources))
+	for name, ds := range m.sources {
+		names = append(names, name)
+		sources[name] = ds
+	}
+	m.mu.Unlock()
+
+	sort.Strings(names)
+	stats := make([]DataSourceStats, 0, len(names))
+	for _, name := range names {
+		ds := sources[name]
+		ds.mu.Lock()
+		s := DataSourceStats{Name: name, Driver: ds.conf.Driver, Host: ds.conf.Host, Dbname: ds.conf.Dbname,
+			Connected: ds.pool != nil, LastUsed: ds.lastUsed}
+		if ds.pool != nil {
+			if last := time.Unix(0, ds.pool.lastUsed.Load()); last.After(s.LastUsed) {
+				s.LastUsed = last
+			}
+			if sqlDB, err := ds.pool.db.DB(); err == nil {
+				s.DBStats = sqlDB.Stats()
+			}
+		}
+		ds.mu.Unlock()
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// Close 关闭全部连接池并停止空闲检查
+func (m *DataSourceManager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.done)
+	sources, retired := m.sources, m.retired
+	m.sources, m.retired = map[string]*dataSource{}, nil
+	m.mu.Unlock()
+	for _, ds := range sources {
+		ds.mu.Lock()
+		ds.pool.close()
+		ds.pool = nil
+		ds.mu.Unlock()
+	}
+	for _, p := range retired {
+		p.close()
+	}
+}
+
+// acquire 返回数据源的连接池，未连接时按注册的配置连接
+func (m *DataSourceManager) acquire(name string) (*pool, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, errors.New("数据源管理器已关闭")
+	}
+	ds, ok := m.sources[name]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w：%s", ErrDataSourceNotFound, name)
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.lastUsed = time.Now()
+	if ds.pool != nil {
+		ds.pool.touch()
+		return ds.pool, nil
+	}
+	db, err := Open(ds.conf, m.opts...)
+	if err != nil {
+		return nil, err
+	}
+	p := &pool{name: name, db: db}
+	p.touch()
+	if err = p.register(); err != nil {
+		logx.Errorf("注册数据源 %s 回调出错: %v", name, err)
+	}
+	ds.pool = p
+	if m.idleTTL > 0 {
+		m.startLoop()
+	}
+	return p, nil
+}
+
+// retire 停用连接池，待使用结束后由空闲检查关闭，调用方持有 ds.mu
+func (m *DataSourceManager) retire(p *pool) {
+	if p == nil {
+		return
+	}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		p.close()
+		return
+	}
+	m.retired = append(m.retired, p)
+	m.mu.Unlock()
+	m.startLoop()
+}
+
+func (m *DataSourceManager) startLoop() {
+	m.start.Do(func() {
+		threading.GoSafe(m.loop)
+	})
+}
+
+// ttl 连接池空闲多久后关闭，idleTTL 为 0 时仅用于停用的连接池
+func (m *DataSourceManager) ttl() time.Duration {
+	if m.idleTTL > 0 {
+		return m.idleTTL
+	}
+	return retiredTTL
+}
+
+func (m *DataSourceManager) loop() {
+	interval := m.ttl() / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.evict()
+		}
+	}
+}
+
+// evict 关闭空闲的连接池：未被取出、最后使用超过 idleTTL 且没有使用中的连接
+func (m *DataSourceManager) evict() {
+	ttl := m.ttl()
+	m.mu.Lock()
+	sources := make([]*dataSource, 0, len(m.sources))
+	for _, ds := range m.sources {
+		sources = append(sources, ds)
+	}
+	var idle []*pool
+	retired := m.retired[:0]
+	for _, p := range m.retired {
+		if p.idle(ttl) {
+			idle = append(idle, p)
+		} else {
+			retired = append(retired, p)
+		}
+	}
+	m.retired = retired
+	m.mu.Unlock()
+	for _, p := range idle {
+		p.close()
+	}
+	if m.idleTTL <= 0 {
+		return
+	}
+	for _, ds := range sources {
+		ds.mu.Lock()
+		if ds.pool != nil && ds.pool.idle(ttl) {
+			ds.pool.close()
+			ds.pool = nil
+		}
+		ds.mu.Unlock()
+	}
+}
+
+// register 注册回调，每次执行语句时记录最后使用时间
+func (p *pool) register() error {
+	touch := func(*gorm.DB) { p.touch() }
+	cb := p.db.Callback()
+	if err := cb.Create().Before("gorm:create").Register(datasourceCallbackName, touch); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register(datasourceCallbackName, touch); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register(datasourceCallbackName, touch); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register(datasourceCallbackName, touch); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register(datasourceCallbackName, touch); err != nil {
+		return err
+	}
+	return cb.Raw().Before("gorm:raw").Register(datasourceCallbackName, touch)
+}
+
+func (p *pool) touch() {
+	p.lastUsed.Store(time.Now().UnixNano())
+}
+
+// idle 连接池是否可以关闭
+func (p *pool) idle(ttl time.Duration) bool {
+	if p.refs.Load() > 0 || time.Since(time.Unix(0, p.lastUsed.Load())) <= ttl {
+		return false
+	}
+	sqlDB, err := p.db.DB()
+	return err != nil || sqlDB.Stats().InUse == 0
+}
+
+// close 关闭连接池
+func (p *pool) close() {
+	if p == nil {
+		return
+	}
+	if sqlDB, err := p.db.DB(); err == nil {
+		if err = sqlDB.Close(); err != nil {
+			logx.Errorf("关闭数据源 %s 出错: %v", p.name, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package configx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/qiaogw/sub-sdk/gormx/plugins"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+type (
+	// DriverFactory 根据配置创建 gorm 方言，通过 Register 注册
+	DriverFactory func(conf DbConf) (gorm.Dialector, error)
+
+	// Option 定义 Open 的可选参数
+	Option func(o *openOptions)
+
+	openOptions struct {
+		config  *gorm.Config
+		plugins []gorm.Plugin
+	}
+)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]DriverFactory{}
+)
+
+func init() {
+	Register(string(MySQL), func(conf DbConf) (gorm.Dialector, error) {
+		m := Mysql{Host: conf.Host, Port: conf.Port, Dbname: conf.Dbname, Username: conf.Username,
+			Password: conf.Password, Config: conf.Config}
+		return mysql.New(mysql.Config{DSN: m.Dsn()}), nil
+	})
+	Register(string(Postgres), func(conf DbConf) (gorm.Dialector, error) {
+		p := PgSql{Host: conf.Host, Port: conf.Port, Dbname: conf.Dbname, Username: conf.Username,
+			Password: conf.Password, TimeZone: conf.TimeZone, SslMode: conf.SslMode, Schema: conf.Schema}
+		return postgres.New(postgres.Config{
+			DSN:                  p.Dsn(),
+			PreferSimpleProtocol: true, // 禁用隐式预处理语句的使用
+		}), nil
+	})
+	Register(string(Sqlite), func(conf DbConf) (gorm.Dialector, error) {
+		s := Sqlite3{Host: conf.Host, Dbname: conf.Dbname}
+		return sqlite.Open(s.Dsn()), nil
+	})
+}
+
+// Register 注册数据库驱动，name 对应 DbConf.Driver，重复注册时覆盖，用于第三方扩展方言
+func Register(name string, factory DriverFactory) {
+	if factory == nil {
+		panic("configx: Register driver factory is nil")
+	}
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[name] = factory
+}
+
+// Drivers 返回已注册的驱动名
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithGormConfig 以 cfg 为基础配置，未设置 Logger、NamingStrategy 时按 DbConf 生成；cfg 不会被修改
+func WithGormConfig(cfg *gorm.Config) Option {
+	return func(o *openOptions) {
+		o.config = cfg
+	}
+}
+
+// WithPlugins 在 DbConf.Plugins 之外追加启用的插件
+func WithPlugins(plugins ...gorm.Plugin) Option {
+	return func(o *openOptions) {
+		o.plugins = append(o.plugins, plugins...)
+	}
+}
+
+// Open 按 conf.Driver 对应的驱动连接数据库，应用日志、命名策略、连接池和插件配置
+func Open(conf DbConf, opts ...Option) (*gorm.DB, error) {
+	var o openOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if conf.Dbname == "" {
+		return nil, errors.New("database name is empty")
+	}
+	driversMu.RLock()
+	factory, ok := drivers[conf.Driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("只支持 %v,不支持的数据库驱动：%s", Drivers(), conf.Driver)
+	}
+	dialector, err := factory(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	// gorm.Open 与 db.Use 会改写配置，使用副本；调用方传入的插件由 gorm.Open 初始化
+	var cfg gorm.Config
+	if o.config != nil {
+		cfg = *o.config
+		if o.config.Plugins != nil {
+			cfg.Plugins = make(map[string]gorm.Plugin, len(o.config.Plugins))
+			for name, p := range o.config.Plugins {
+				cfg.Plugins[name] = p
+			}
+		}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = NewDefaultZeroLogger(conf)
+	}
+	if cfg.NamingStrategy == nil {
+		cfg.NamingStrategy = schema.NamingStrategy{
+			TablePrefix:   conf.TablePrefix,
+			SingularTable: conf.SingularTable || conf.Driver == string(Sqlite),
+		}
+	}
+	db, err := gorm.Open(dialector, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err = setupConn(db, conf, o.plugins); err != nil {
+		if sqlDB, e := db.DB(); e == nil {
+			_ = sqlDB.Close()
+		}
+		return nil, err
+	}
+	logx.Infof("✅ 数据库连接成功：%s", conf.Driver+"|"+conf.Host+"|"+conf.Dbname)
+	return db, nil
+}
+
+// setupConn 启用插件并设置连接池参数，gorm.Config 中已有的插件跳过
+func setupConn(db *gorm.DB, conf DbConf, extra []gorm.Plugin) error {
+	if err := plugins.Use(db, conf.Plugins...); err != nil {
+		return err
+	}
+	for _, p := range extra {
+		if _, ok := db.Config.Plugins[p.Name()]; ok {
+			continue
+		}
+		if err := db.Use(p); err != nil {
+			return err
+		}
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxIdleConns(conf.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(conf.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(conf.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(conf.ConnMaxIdleTime)
+	return nil
+}
+
+// GetGormLogMode 根据配置返回 Gorm 的日志级别
+func (conf DbConf) GetGormLogMode() gormLogger.LogLevel {
+	return OverwriteGormLogMode(conf.LogMode)
+}
+
+// GetSlowThreshold 返回慢查询阈值，并转换为 time.Duration 类型（单位为毫秒）
+func (conf DbConf) GetSlowThreshold() time.Duration {
+	return time.Duration(conf.SlowThreshold) * time.Millisecond
+}
+
+// GetColorful 返回是否启用日志彩色输出的配置
+func (conf DbConf) GetColorful() bool {
+	return conf.LogColorful
+}
+
+// Connect 按配置连接数据库，同 Open
+func (conf DbConf) Connect() (*gorm.DB, error) {
+	return Open(conf)
+}
+
+// ConnectWithConfig 以自定义的 gorm.Config 连接数据库
+func (conf DbConf) ConnectWithConfig(cfg *gorm.Config) (*gorm.DB, error) {
+	return Open(conf, WithGormConfig(cfg))
+}
@@ -0,0 +1,151 @@
+package configx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type driverUser struct {
+	Id   int64
+	Name string
+}
+
+// countPlugin 记录初始化次数的测试插件
+type countPlugin struct {
+	n int
+}
+
+func (p *countPlugin) Name() string { return "count" }
+
+func (p *countPlugin) Initialize(*gorm.DB) error {
+	p.n++
+	return nil
+}
+
+func TestOpen(t *testing.T) {
+	conf := DbConf{
+		Driver:          string(Sqlite),
+		Host:            t.TempDir(),
+		Dbname:          "test",
+		MaxOpenConns:    3,
+		ConnMaxLifetime: time.Minute,
+		TablePrefix:     "it_",
+		SingularTable:   true,
+		LogMode:         "silent",
+	}
+	db, err := Open(conf)
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&driverUser{}))
+	assert.True(t, db.Migrator().HasTable("it_driver_user"))
+	sqlDB, _ := db.DB()
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+	_, ok := db.Plugins["gorm-zero-tracing-plugin"]
+	assert.True(t, ok)
+
+	// 同一 gorm.Config 可重复使用，其中的插件保留且不修改调用方的配置
+	counter := &countPlugin{}
+	cfg := &gorm.Config{Plugins: map[string]gorm.Plugin{counter.Name(): counter}}
+	for i := 0; i < 2; i++ {
+		db, err = Open(conf, WithGormConfig(cfg))
+		assert.Nil(t, err)
+		_, ok = db.Plugins[counter.Name()]
+		assert.True(t, ok)
+	}
+	assert.Equal(t, 2, counter.n)
+	assert.Len(t, cfg.Plugins, 1)
+
+	// sqlite 默认使用单数表名，与 Sqlite3.Connect 一致
+	conf.SingularTable, conf.TablePrefix = false, ""
+	db, err = Open(conf)
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&driverUser{}))
+	assert.True(t, db.Migrator().HasTable("driver_user"))
+	db, err = (&Sqlite3{Host: conf.Host, Dbname: conf.Dbname, MaxOpenConns: 2, LogMode: "silent"}).Connect()
+	assert.Nil(t, err)
+	assert.True(t, db.Migrator().HasTable("driver_user"))
+	sqlDB, _ = db.DB()
+	assert.Equal(t, 2, sqlDB.Stats().MaxOpenConnections)
+
+	// 第三方驱动与插件选择
+	Register("memory", func(conf DbConf) (gorm.Dialector, error) {
+		return sqlite.Open(":memory:"), nil
+	})
+	assert.Contains(t, Drivers(), "memory")
+	db, err = Open(DbConf{Driver: "memory", Dbname: "test"})
+	assert.Nil(t, err)
+	assert.False(t, db.Migrator().HasTable("it_driver_user"))
+
+	_, err = Open(DbConf{Driver: "memory", Dbname: "test", Plugins: []string{"unknown"}})
+	assert.NotNil(t, err)
+	_, err = Open(DbConf{Driver: "oracle", Dbname: "test"})
+	assert.NotNil(t, err)
+}
+
+func TestDataSourceManager(t *testing.T) {
+	m := NewDataSourceManager(time.Hour)
+	defer m.Close()
+	conf := DbConf{Driver: string(Sqlite), Host: t.TempDir(), Dbname: "a", MaxOpenConns: 2, LogMode: "silent"}
+	_, err := m.Get("a")
+	assert.ErrorIs(t, err, ErrDataSourceNotFound)
+
+	// 复用连接池，配置不变时不重新连接
+	db, err := m.Open("a", conf)
+	assert.Nil(t, err)
+	again, err := m.Open("a", conf)
+	assert.Nil(t, err)
+	assert.Same(t, db, again)
+	stats := m.Stats()
+	assert.Len(t, stats, 1)
+	assert.True(t, stats[0].Connected)
+	assert.Equal(t, 2, stats[0].MaxOpenConnections)
+
+	// 配置变化时改用新连接池，旧连接池在使用结束前不关闭
+	conf.MaxOpenConns = 3
+	m.Set("a", conf)
+	assert.Nil(t, db.Exec("SELECT 1").Error)
+	m.evict()
+	assert.Nil(t, db.Exec("SELECT 1").Error)
+	db, err = m.Get("a")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, m.Stats()[0].MaxOpenConnections)
+
+	m.Remove("a")
+	assert.Empty(t, m.Stats())
+	assert.Nil(t, db.Exec("SELECT 1").Error)
+
+	// 空闲超时关闭，再次使用时重新连接
+	idle := NewDataSourceManager(time.Millisecond)
+	defer idle.Close()
+	_, err = idle.Open("a", conf)
+	assert.Nil(t, err)
+	time.Sleep(2 * time.Millisecond)
+	idle.evict()
+	assert.False(t, idle.Stats()[0].Connected)
+	db, err = idle.Get("a")
+	assert.Nil(t, err)
+	assert.Nil(t, db.Exec("SELECT 1").Error)
+
+	// 取出的连接释放前不关闭，配置变化后释放时关闭旧连接池
+	db, release, err := idle.Acquire("a")
+	assert.Nil(t, err)
+	time.Sleep(2 * time.Millisecond)
+	idle.evict()
+	assert.True(t, idle.Stats()[0].Connected)
+	conf.MaxOpenConns = 4
+	idle.Set("a", conf)
+	time.Sleep(2 * time.Millisecond)
+	idle.evict()
+	assert.Nil(t, db.Exec("SELECT 1").Error)
+	release()
+	release()
+	time.Sleep(2 * time.Millisecond)
+	idle.evict()
+	assert.NotNil(t, db.Exec("SELECT 1").Error)
+	db, err = idle.Get("a")
+	assert.Nil(t, err)
+	assert.Nil(t, db.Exec("SELECT 1").Error)
+}
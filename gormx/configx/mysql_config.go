@@ -1,13 +1,11 @@
 package configx
 
 import (
-	"errors"
 	"fmt"
-	"github.com/qiaogw/sub-sdk/gormx/plugins"
-	"gorm.io/driver/mysql"
+	"time"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-	"time"
 )
 
 // Mysql 定义了连接 MySQL 数据库所需的配置信息
@@ -46,61 +44,18 @@ func (m *Mysql) GetColorful() bool {
 	return m.LogColorful
 }
 
-// Connect 根据 Mysql 配置连接 MySQL 数据库，返回 *gorm.DB 对象以及可能出现的错误
+// Connect 根据 Mysql 配置连接 MySQL 数据库，同 Open，应用连接池、日志和默认插件配置
 func (m *Mysql) Connect() (*gorm.DB, error) {
-	// 如果数据库名称为空，则返回错误
-	if m.Dbname == "" {
-		return nil, errors.New("database name is empty")
-	}
-	// 构造 mysql 驱动所需的配置
-	mysqlCfg := mysql.Config{
-		DSN: m.Dsn(),
-	}
-	// 创建默认的 Gorm 日志实例
-	newLogger := NewDefaultGormLogger(m)
-	// 使用 gorm.Open 打开数据库连接
-	db, err := gorm.Open(mysql.New(mysqlCfg), &gorm.Config{
-		Logger: newLogger,
-	})
-	if err != nil {
-		return nil, err
-	} else {
-		// 获取底层数据库连接对象，并设置连接池参数
-		sqldb, _ := db.DB()
-		sqldb.SetMaxIdleConns(m.MaxIdleConns)
-		sqldb.SetMaxOpenConns(m.MaxOpenConns)
-		return db, nil
-	}
+	return Open(m.dbConf())
 }
 
-// ConnectWithConfig 根据 Mysql 配置和自定义的 gorm.Config 连接 MySQL 数据库，初始化插件后返回 *gorm.DB 对象及可能出现的错误
+// ConnectWithConfig 根据 Mysql 配置和自定义的 gorm.Config 连接 MySQL 数据库，同 Open(conf, WithGormConfig(cfg))
 func (m *Mysql) ConnectWithConfig(cfg *gorm.Config) (*gorm.DB, error) {
-	// 如果数据库名称为空，则返回错误
-	if m.Dbname == "" {
-		return nil, errors.New("database name is empty")
-	}
-	// 构造 mysql 驱动所需的配置
-	mysqlCfg := mysql.Config{
-		DSN: m.Dsn(),
-	}
-	// 使用默认的 Gorm 日志配置创建 Logger
-	newLogger := NewDefaultZeroLogger(m)
-	cfg.Logger = newLogger
-	// 使用自定义配置连接数据库
-	db, err := gorm.Open(mysql.New(mysqlCfg), cfg)
-	if err != nil {
-		return nil, err
-	}
-
-	// 初始化数据库插件
-	err = plugins.InitPlugins(db)
-	if err != nil {
-		return nil, err
-	}
+	return Open(m.dbConf(), WithGormConfig(cfg))
+}
 
-	// 获取底层数据库连接对象，并设置连接池参数
-	sqldb, _ := db.DB()
-	sqldb.SetMaxIdleConns(m.MaxIdleConns)
-	sqldb.SetMaxOpenConns(m.MaxOpenConns)
-	return db, nil
+func (m *Mysql) dbConf() DbConf {
+	return DbConf{Driver: string(MySQL), Host: m.Host, Port: m.Port, Dbname: m.Dbname, Username: m.Username,
+		Password: m.Password, Config: m.Config, MaxIdleConns: m.MaxIdleConns, MaxOpenConns: m.MaxOpenConns,
+		LogMode: m.LogMode, LogColorful: m.LogColorful, SlowThreshold: m.SlowThreshold}
 }
@@ -1,14 +1,11 @@
 package configx
 
 import (
-	"errors"
 	"fmt"
-	"github.com/qiaogw/sub-sdk/gormx/plugins"
-	"github.com/zeromicro/go-zero/core/logx"
-	"gorm.io/driver/postgres"
+	"time"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-	"time"
 )
 
 // PgSql 定义了 PostgreSQL 数据库的配置信息
@@ -53,64 +50,19 @@ func (m *PgSql) GetColorful() bool {
 	return m.LogColorful
 }
 
-// Connect 根据 PgSql 配置连接 PostgreSQL 数据库，返回 *gorm.DB 对象和可能出现的错误
+// Connect 根据 PgSql 配置连接 PostgreSQL 数据库，同 Open，应用连接池、日志和默认插件配置
 func (m *PgSql) Connect() (*gorm.DB, error) {
-	// 如果数据库名称为空，则返回错误
-	if m.Dbname == "" {
-		return nil, errors.New("database name is empty")
-	}
-	// 使用默认的 Gorm 日志配置创建 Logger
-	newLogger := NewDefaultGormLogger(m)
-	// 构造 postgres 配置
-	pgsqlCfg := postgres.Config{
-		DSN:                  m.Dsn(),
-		PreferSimpleProtocol: true, // 禁用隐式预处理语句的使用
-	}
-	// 使用 gorm.Open 打开数据库连接
-	db, err := gorm.Open(postgres.New(pgsqlCfg), &gorm.Config{
-		Logger: newLogger,
-	})
-	if err != nil {
-		return nil, err
-	} else {
-		// 获取底层数据库连接对象，并设置最大空闲连接数和最大打开连接数
-		sqldb, _ := db.DB()
-		sqldb.SetMaxIdleConns(m.MaxIdleConns)
-		sqldb.SetMaxOpenConns(m.MaxOpenConns)
-		return db, nil
-	}
+	return Open(m.dbConf())
 }
 
-// ConnectWithConfig 根据 PgSql 配置以及自定义的 gorm.Config 连接 PostgreSQL 数据库
-// 并初始化插件，返回 *gorm.DB 对象和可能出现的错误
+// ConnectWithConfig 根据 PgSql 配置以及自定义的 gorm.Config 连接 PostgreSQL 数据库，同 Open(conf, WithGormConfig(cfg))
 func (m *PgSql) ConnectWithConfig(cfg *gorm.Config) (*gorm.DB, error) {
-	// 如果数据库名称为空，则返回错误
-	if m.Dbname == "" {
-		return nil, errors.New("database name is empty")
-	}
-	// 构造 postgres 配置
-	pgsqlCfg := postgres.Config{
-		DSN:                  m.Dsn(),
-		PreferSimpleProtocol: true, // 禁用隐式预处理语句的使用
-	}
-	// 使用默认的 Gorm 日志配置创建 Logger
-	newLogger := NewDefaultZeroLogger(m)
-	cfg.Logger = newLogger
-	// 使用自定义配置打开数据库连接
-	db, err := gorm.Open(postgres.New(pgsqlCfg), cfg)
-	if err != nil {
-		return nil, err
-	}
+	return Open(m.dbConf(), WithGormConfig(cfg))
+}
 
-	// 初始化数据库插件
-	err = plugins.InitPlugins(db)
-	if err != nil {
-		return nil, err
-	}
-	// 获取底层数据库连接对象，并设置最大空闲连接数和最大打开连接数
-	sqldb, _ := db.DB()
-	sqldb.SetMaxIdleConns(m.MaxIdleConns)
-	sqldb.SetMaxOpenConns(m.MaxOpenConns)
-	logx.Infof("✅ 数据库连接成功：%s", m.Driver+"|"+m.Host+"|"+m.Dbname)
-	return db, nil
+func (m *PgSql) dbConf() DbConf {
+	return DbConf{Driver: string(Postgres), Host: m.Host, Port: m.Port, Dbname: m.Dbname, Username: m.Username,
+		Password: m.Password, TimeZone: m.TimeZone, SslMode: m.SslMode, Schema: m.Schema,
+		MaxIdleConns: m.MaxIdleConns, MaxOpenConns: m.MaxOpenConns,
+		LogMode: m.LogMode, LogColorful: m.LogColorful, SlowThreshold: m.SlowThreshold}
 }
@@ -1,16 +1,11 @@
 package configx
 
 import (
-	"errors"
-	"github.com/glebarez/sqlite"
-	"github.com/qiaogw/sub-sdk/gormx/plugins"
-	"github.com/zeromicro/go-zero/core/logx"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-	"gorm.io/gorm/schema"
-
 	"path/filepath"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 // Sqlite3 定义了 Sqlite3 数据库的配置信息
@@ -53,44 +48,18 @@ func (m *Sqlite3) GetColorful() bool {
 	return m.LogColorful
 }
 
-// Connect 根据 Sqlite3 配置连接 PostgreSQL 数据库，返回 *gorm.DB 对象和可能出现的错误
+// Connect 根据 Sqlite3 配置连接 Sqlite 数据库，同 Open，应用连接池、日志和默认插件配置，使用单数表名
 func (m *Sqlite3) Connect() (*gorm.DB, error) {
-	// 如果数据库名称为空，则返回错误
-	if m.Dbname == "" {
-		return nil, errors.New("database name is empty")
-	}
-	newLogger := NewDefaultGormLogger(m)
-	db, err := gorm.Open(sqlite.Open(m.Dsn()), &gorm.Config{
-		NamingStrategy: schema.NamingStrategy{
-			//TablePrefix:   dbCfg.Prefix,
-			SingularTable: true,
-		},
-		Logger: newLogger,
-	})
-	if err != nil {
-		return nil, err
-	}
-	return db, nil
+	return Open(m.dbConf())
 }
 
-// ConnectWithConfig 根据 Sqlite3 配置以及自定义的 gorm.Config 连接 PostgreSQL 数据库
-// 并初始化插件，返回 *gorm.DB 对象和可能出现的错误
+// ConnectWithConfig 根据 Sqlite3 配置以及自定义的 gorm.Config 连接 Sqlite 数据库，同 Open(conf, WithGormConfig(cfg))
 func (m *Sqlite3) ConnectWithConfig(cfg *gorm.Config) (*gorm.DB, error) {
-	// 如果数据库名称为空，则返回错误
-	if m.Dbname == "" {
-		return nil, errors.New("database name is empty")
-	}
-	cfg.Logger = NewDefaultZeroLogger(m)
-	cfg.NamingStrategy = schema.NamingStrategy{
-		SingularTable: true,
-	}
-	db, err := gorm.Open(sqlite.Open(m.Dsn()), cfg)
-	if err != nil {
-		return nil, err
-	}
-	if err := plugins.InitPlugins(db); err != nil {
-		return nil, err
-	}
-	logx.Infof("✅ 数据库连接成功：%s", m.Driver+"|"+m.Host+"|"+m.Dbname)
-	return db, nil
+	return Open(m.dbConf(), WithGormConfig(cfg))
+}
+
+func (m *Sqlite3) dbConf() DbConf {
+	return DbConf{Driver: string(Sqlite), Host: m.Host, Dbname: m.Dbname, SingularTable: true,
+		MaxIdleConns: m.MaxIdleConns, MaxOpenConns: m.MaxOpenConns,
+		LogMode: m.LogMode, LogColorful: m.LogColorful, SlowThreshold: m.SlowThreshold}
 }
@@ -0,0 +1,325 @@
+package gormx
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/qiaogw/sub-sdk/gormx/modelx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor 游标被篡改、已过期或与当前排序不匹配
+var ErrInvalidCursor = errx.NewErrCodeMsg(errx.RequestParamError, "分页游标无效")
+
+var (
+	cursorSecretMu sync.RWMutex
+	cursorSecret   []byte
+)
+
+func init() {
+	// 默认使用进程内随机密钥，多实例部署需通过 SetCursorSecret 配置相同密钥
+	cursorSecret = make([]byte, 32)
+	_, _ = rand.Read(cursorSecret)
+}
+
+// SetCursorSecret 设置游标签名密钥
+func SetCursorSecret(secret []byte) {
+	cursorSecretMu.Lock()
+	defer cursorSecretMu.Unlock()
+	cursorSecret = append([]byte(nil), secret...)
+}
+
+type (
+	// KeysetColumn 游标分页排序列，Column 为数据库列名，可带表名 e.g. user.created_at
+	KeysetColumn struct {
+		Column string
+		Desc   bool
+	}
+
+	// Keyset 游标分页配置，排序列应为非空列，主键自动追加为最后一列保证顺序唯一
+	Keyset struct {
+		Columns    []KeysetColumn
+		PrimaryKey string // 主键列，默认 id
+		Secret     []byte // 签名密钥，默认使用 SetCursorSecret 设置的密钥
+	}
+
+	// CursorPage 游标分页结果
+	CursorPage[T any] struct {
+		List     []T    `json:"list"`
+		Next     string `json:"next"`     // 下一页游标，为空表示没有更多
+		Prev     string `json:"prev"`     // 上一页游标，为空表示已是第一页
+		PageSize int64  `json:"pageSize"` // 每页条数
+	}
+
+	// cursorToken 游标内容
+	cursorToken struct {
+		Prev   bool          `json:"p,omitempty"`
+		Values []cursorValue `json:"v"`
+	}
+
+	// cursorValue 带类型的游标取值，保证解码后与数据库列类型一致
+	cursorValue struct {
+		Kind  string `json:"k"`
+		Value string `json:"v"`
+	}
+)
+
+// NewKeyset 根据排序字段创建游标分页配置，sortBy 为空时仅按主键排序
+// e.g. NewKeyset("created_at", true) 对应 SortBy("created_at", true)
+func NewKeyset(sortBy string, descending bool) Keyset {
+	k := Keyset{}
+	if sortBy != "" {
+		k.Columns = append(k.Columns, KeysetColumn{Column: sortBy, Desc: descending})
+	}
+	return k
+}
+
+// CursorPaginate 游标分页查询，db 可预先应用 MakeCondition 等条件，但不应再设置排序
+// p.Cursor 为空时返回第一页，p.PageSize 为每页条数
+func CursorPaginate[T any](db *gorm.DB, k Keyset, p *modelx.Pagination) (*CursorPage[T], error) {
+	limit := int(p.GetPageSize())
+	columns := k.columns()
+	var token *cursorToken
+	if p.Cursor != "" {
+		var err error
+		if token, err = k.decode(p.Cursor, columns); err != nil {
+			return nil, err
+		}
+	}
+	backward := token != nil && token.Prev
+
+	d := DialectOf(db)
+	tx := db
+	if token != nil {
+		values, err := decodeCursorValues(token.Values)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		sql, args := keysetWhere(d, columns, values, backward)
+		tx = tx.Where(sql, args...)
+	}
+	for _, c := range columns {
+		order := d.Quote(c.Column)
+		if c.Desc != backward {
+			order += " DESC"
+		}
+		tx = tx.Order(order)
+	}
+
+	var list []T
+	if err := tx.Limit(limit + 1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	more := len(list) > limit
+	if more {
+		list = list[:limit]
+	}
+	if backward {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+
+	page := &CursorPage[T]{List: list, PageSize: int64(limit)}
+	if len(list) == 0 {
+		return page, nil
+	}
+	sch, err := schema.Parse(new(T), schemaCache, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	// 按 prev 游标翻页时游标所在行之后必有数据，按 next 游标翻页时之前必有数据
+	if more || backward {
+		if page.Next, err = k.encode(sch, columns, &list[len(list)-1], false); err != nil {
+			return nil, err
+		}
+	}
+	if (more && backward) || (token != nil && !backward) {
+		if page.Prev, err = k.encode(sch, columns, &list[0], true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// schemaCache 结果结构体的 gorm schema 缓存
+var schemaCache = &sync.Map{}
+
+// columns 返回排序列，末尾追加主键
+func (k Keyset) columns() []KeysetColumn {
+	pk := k.PrimaryKey
+	if pk == "" {
+		pk = "id"
+	}
+	columns := make([]KeysetColumn, 0, len(k.Columns)+1)
+	var desc bool
+	for _, c := range k.Columns {
+		if columnName(c.Column) == columnName(pk) {
+			continue
+		}
+		columns = append(columns, c)
+		desc = c.Desc
+	}
+	// 主键方向与最后一个排序列一致，便于使用联合索引
+	return append(columns, KeysetColumn{Column: pk, Desc: desc})
+}
+
+// fingerprint 排序列签名，保证游标只能用于生成它的排序
+func (k Keyset) fingerprint(columns []KeysetColumn) string {
+	parts := make([]string, len(columns))
+	for i, c := range columns {
+		parts[i] = fmt.Sprintf("%s:%t", c.Column, c.Desc)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (k Keyset) sign(payload []byte, columns []KeysetColumn) []byte {
+	secret := k.Secret
+	if len(secret) == 0 {
+		cursorSecretMu.RLock()
+		secret = cursorSecret
+		cursorSecretMu.RUnlock()
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(k.fingerprint(columns)))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// encode 从行数据中取排序列的值并生成签名游标
+func (k Keyset) encode(sch *schema.Schema, columns []KeysetColumn, row interface{}, prev bool) (string, error) {
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	token := cursorToken{Prev: prev, Values: make([]cursorValue, len(columns))}
+	for i, c := range columns {
+		field := sch.LookUpField(columnName(c.Column))
+		if field == nil {
+			return "", fmt.Errorf("游标列 %s 不在结果结构体 %s 中", c.Column, sch.Name)
+		}
+		v, _ := field.ValueOf(context.Background(), rv)
+		token.Values[i] = encodeCursorValue(v)
+	}
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(k.sign(payload, columns)), nil
+}
+
+// decode 校验签名并解析游标
+func (k Keyset) decode(cursor string, columns []KeysetColumn) (*cursorToken, error) {
+	enc := base64.RawURLEncoding
+	data, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := enc.DecodeString(data)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := enc.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, k.sign(payload, columns)) {
+		return nil, ErrInvalidCursor
+	}
+	token := &cursorToken{}
+	if err := json.Unmarshal(payload, token); err != nil || len(token.Values) != len(columns) {
+		return nil, ErrInvalidCursor
+	}
+	return token, nil
+}
+
+// keysetWhere 生成 (c1 > ?) OR (c1 = ? AND c2 > ?) ... 形式的条件，兼容各列排序方向不同的情况
+func keysetWhere(d SearchDialect, columns []KeysetColumn, values []interface{}, backward bool) (string, []interface{}) {
+	ors := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns)*(len(columns)+1)/2)
+	for i, c := range columns {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, d.Quote(columns[j].Column)+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if c.Desc != backward {
+			op = " < ?"
+		}
+		ands = append(ands, d.Quote(c.Column)+op)
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func encodeCursorValue(v interface{}) cursorValue {
+	switch x := v.(type) {
+	case time.Time:
+		return cursorValue{Kind: "t", Value: x.Format(time.RFC3339Nano)}
+	case *time.Time:
+		if x != nil {
+			return cursorValue{Kind: "t", Value: x.Format(time.RFC3339Nano)}
+		}
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursorValue{Kind: "i", Value: strconv.FormatInt(rv.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursorValue{Kind: "u", Value: strconv.FormatUint(rv.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{Kind: "f", Value: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}
+	case reflect.Bool:
+		return cursorValue{Kind: "b", Value: strconv.FormatBool(rv.Bool())}
+	}
+	return cursorValue{Kind: "s", Value: fmt.Sprint(v)}
+}
+
+func decodeCursorValues(values []cursorValue) ([]interface{}, error) {
+	out := make([]interface{}, len(values))
+	var err error
+	for i, v := range values {
+		switch v.Kind {
+		case "t":
+			out[i], err = time.Parse(time.RFC3339Nano, v.Value)
+		case "i":
+			out[i], err = strconv.ParseInt(v.Value, 10, 64)
+		case "u":
+			out[i], err = strconv.ParseUint(v.Value, 10, 64)
+		case "f":
+			out[i], err = strconv.ParseFloat(v.Value, 64)
+		case "b":
+			out[i], err = strconv.ParseBool(v.Value)
+		case "s":
+			out[i] = v.Value
+		default:
+			err = errors.New("unknown cursor value kind")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// columnName 去掉表名前缀
+func columnName(column string) string {
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		return column[i+1:]
+	}
+	return column
+}
@@ -0,0 +1,99 @@
+package gormx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/qiaogw/sub-sdk/gormx/modelx"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newCursorDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	assert.Nil(t, db.AutoMigrate(&searchUser{}))
+	users := make([]searchUser, 0, 7)
+	for i := 1; i <= 7; i++ {
+		users = append(users, searchUser{Id: int64(i), Name: "u", Status: int64(i % 3)})
+	}
+	assert.Nil(t, db.Create(&users).Error)
+	return db
+}
+
+func cursorIds(list []searchUser) []int64 {
+	ids := make([]int64, len(list))
+	for i, u := range list {
+		ids[i] = u.Id
+	}
+	return ids
+}
+
+func TestCursorPaginate(t *testing.T) {
+	db := newCursorDB(t)
+	k := NewKeyset("status", true)
+	p := &modelx.Pagination{PageSize: 3}
+
+	page, err := CursorPaginate[searchUser](db.Model(&searchUser{}), k, p)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{5, 2, 7}, cursorIds(page.List))
+	assert.Empty(t, page.Prev)
+
+	p.Cursor = page.Next
+	page, err = CursorPaginate[searchUser](db.Model(&searchUser{}), k, p)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{4, 1, 6}, cursorIds(page.List))
+	assert.NotEmpty(t, page.Prev)
+
+	p.Cursor = page.Next
+	last, err := CursorPaginate[searchUser](db.Model(&searchUser{}), k, p)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{3}, cursorIds(last.List))
+	assert.Empty(t, last.Next)
+
+	p.Cursor = page.Prev
+	page, err = CursorPaginate[searchUser](db.Model(&searchUser{}), k, p)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{5, 2, 7}, cursorIds(page.List))
+	assert.Empty(t, page.Prev)
+	assert.NotEmpty(t, page.Next)
+
+	// 游标与 MakeCondition 条件组合
+	type query struct {
+		Status []int64 `search:"type:in;table:user;column:status"`
+	}
+	p.Cursor = ""
+	p.PageSize = 2
+	page, err = CursorPaginate[searchUser](db.Model(&searchUser{}).
+		Scopes(MakeCondition(query{Status: []int64{0, 1}}, Sqlite)), k, p)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{7, 4}, cursorIds(page.List))
+}
+
+func TestCursorPaginateTampered(t *testing.T) {
+	db := newCursorDB(t)
+	k := NewKeyset("status", false)
+	page, err := CursorPaginate[searchUser](db.Model(&searchUser{}), k, &modelx.Pagination{PageSize: 2})
+	assert.Nil(t, err)
+
+	cases := []struct {
+		keyset Keyset
+		cursor string
+	}{
+		{k, page.Next + "x"},
+		{k, "abc"},
+		// 游标不能用于其它排序
+		{NewKeyset("status", true), page.Next},
+		{Keyset{Columns: k.Columns, Secret: []byte("other")}, page.Next},
+	}
+	for _, c := range cases {
+		_, err = CursorPaginate[searchUser](db.Model(&searchUser{}), c.keyset, &modelx.Pagination{PageSize: 2, Cursor: c.cursor})
+		var codeErr *errx.CodeError
+		assert.True(t, errors.As(err, &codeErr))
+		assert.Equal(t, errx.RequestParamError, codeErr.GetErrCode())
+	}
+}
@@ -3,7 +3,12 @@ package gormx
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/qiaogw/sub-sdk/errx"
 	"gorm.io/gorm"
 )
 
@@ -11,41 +16,107 @@ const (
 	PermissionKey = "dataPermission"
 )
 
+// legacyIdPattern DataFilter 中允许的用户 id
+var legacyIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 type DataPermission struct {
-	DataScope  string
-	UserId     interface{}
-	DeptId     interface{}
-	RoleId     interface{}
-	DataFilter string //自定义条件create_by in 用户id范围
+	DataScope string
+	UserId    interface{}
+	DeptId    interface{}
+	RoleId    interface{}
+	// Deprecated: 请通过 DataRole.UserIds 指定自定数据范围
+	// 仅支持逗号分隔的用户 id 列表，作为未指定部门和用户的自定数据范围角色的 UserIds；其他内容返回错误
+	DataFilter string
+	Roles      []DataRole // 用户的全部角色，多个角色的数据范围取并集；为空时使用 DataScope、RoleId
 }
 
-// PermissionData 数据权限
+// DataRole 角色的数据范围
+type DataRole struct {
+	RoleId    interface{}
+	DataScope string
+	DeptIds   []interface{} // 自定数据范围可见的部门
+	UserIds   []interface{} // 自定数据范围可见的用户
+}
+
+// PermissionData 数据权限，按 DefaultDataScopeConfig 的表名和字段名生成条件
 func PermissionData(tableName string, p *DataPermission) func(db *gorm.DB) *gorm.DB {
+	return DefaultDataScopeConfig.PermissionData(tableName, p)
+}
+
+// PermissionData 数据权限，tableName 为业务表名或别名
+// 多个角色的条件以 OR 连接，任一角色为全部数据时不限制；未配置数据范围时不限制
+func (c *DataScopeConfig) PermissionData(tableName string, p *DataPermission) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
-		// if !config.ApplicationConfig.EnableDP {
-		// 	return db
-		// }
-		switch p.DataScope {
-		case "2": //自定数据权限
-			where := fmt.Sprintf("(%s)", p.DataFilter)
-			return db.Where(tableName + ".create_by in " + where)
-		case "3": //本部门数据权限
-			return db.Where(tableName+".create_by in "+
-				"(SELECT user_id from admin_user where dept_id = ? )", p.DeptId)
-		case "4": //本部门及以下数据权限
-			return db.Where(tableName+".create_by in "+
-				"(SELECT user_id from admin_user "+
-				"where admin_user.dept_id in "+
-				"(with RECURSIVE temp_child as "+
-				"(select * from admin_dept where id = ?  union all "+
-				"select c.* from admin_dept as c,temp_child t where c.parent_id=t.id) "+
-				"select id from temp_child)", p.DeptId)
-		case "5": //仅本人数据权限
-			return db.Where(tableName+".create_by = ?", p.UserId)
-		default:
+		expr, args, all, err := c.condition(DialectOf(db), tableName, p)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		if all {
 			return db
 		}
+		return db.Where(expr, args...)
+	}
+}
+
+// condition 生成数据权限条件，all 为 true 时不限制
+func (c *DataScopeConfig) condition(d SearchDialect, tableName string, p *DataPermission) (string, []interface{}, bool, error) {
+	if p == nil {
+		return "", nil, true, nil
+	}
+	roles := p.Roles
+	if len(roles) == 0 && p.DataScope != "" {
+		roles = []DataRole{{RoleId: p.RoleId, DataScope: p.DataScope}}
+	}
+	if len(roles) == 0 {
+		return "", nil, true, nil
+	}
+	ctx := &DataScopeContext{Config: c, Dialect: d, Table: tableName, Permission: p}
+	exprs := make([]string, 0, len(roles))
+	var args []interface{}
+	for _, role := range roles {
+		if role.DataScope == DataScopeCustom && p.DataFilter != "" && len(role.DeptIds) == 0 && len(role.UserIds) == 0 {
+			ids, err := legacyDataFilter(p.DataFilter)
+			if err != nil {
+				return "", nil, false, err
+			}
+			role.UserIds = ids
+		}
+		s, ok := LookupDataScope(role.DataScope)
+		if !ok {
+			return "", nil, false, fmt.Errorf("未注册的数据范围: %s", role.DataScope)
+		}
+		ctx.Role = role
+		expr, a, all := s.Condition(ctx)
+		if all {
+			return "", nil, true, nil
+		}
+		exprs = append(exprs, expr)
+		args = append(args, a...)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], args, false, nil
+	}
+	return "(" + strings.Join(exprs, " OR ") + ")", args, false, nil
+}
+
+// legacyDataFilter 将旧版 DataFilter 的用户 id 列表解析为参数，数字 id 转为整数
+func legacyDataFilter(filter string) ([]interface{}, error) {
+	parts := strings.Split(filter, ",")
+	ids := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		id := strings.Trim(strings.TrimSpace(part), "'\"")
+		if !legacyIdPattern.MatchString(id) {
+			return nil, errx.NewErrorf(errx.RequestParamError,
+				"DataFilter 只支持逗号分隔的用户 id 列表，请改用 DataRole.UserIds：%s", filter)
+		}
+		if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+			ids = append(ids, n)
+		} else {
+			ids = append(ids, id)
+		}
 	}
+	return ids, nil
 }
 
 func getPermissionFromContext(c *gin.Context) *DataPermission {
@@ -64,17 +135,78 @@ func GetPermissionFromContext(c *gin.Context) *DataPermission {
 	return getPermissionFromContext(c)
 }
 
-func newDataPermission(tx *gorm.DB, userId interface{}) (*DataPermission, error) {
-	var err error
-	p := &DataPermission{}
-	err = tx.Table("sys_user").
-		Select("sys_user.user_id", "sys_role.role_id", "sys_user.dept_id", "sys_role.data_scope").
-		Joins("left join sys_role on sys_role.role_id = sys_user.role_id").
-		Where("sys_user.user_id = ?", userId).
-		Scan(p).Error
+// LoadDataPermission 按 DefaultDataScopeConfig 查询用户的部门及全部角色的数据范围
+func LoadDataPermission(tx *gorm.DB, userId interface{}) (*DataPermission, error) {
+	return DefaultDataScopeConfig.LoadDataPermission(tx, userId)
+}
+
+// LoadDataPermission 查询用户的部门及全部角色的数据范围，自定数据范围的角色同时加载可见部门
+func (c *DataScopeConfig) LoadDataPermission(tx *gorm.DB, userId interface{}) (*DataPermission, error) {
+	tx = tx.Session(&gorm.Session{NewDB: true, Context: tx.Statement.Context})
+	d := DialectOf(tx)
+	p := &DataPermission{UserId: userId}
+	var deptIds []interface{}
+	err := tx.Table(c.UserTable).
+		Where(columnRef(d, c.UserTable, c.UserIdColumn)+" = ?", userId).
+		Limit(1).
+		Pluck(c.UserDeptColumn, &deptIds).Error
+	if err != nil {
+		return nil, errors.New("获取用户数据出错 msg:" + err.Error())
+	}
+	if len(deptIds) == 0 {
+		return nil, fmt.Errorf("获取用户数据出错 msg:用户 %v 不存在", userId)
+	}
+	p.DeptId = scanValue(deptIds[0])
+
+	rows, err := tx.Table(c.RoleTable).
+		Select(columnRef(d, c.RoleTable, c.RoleIdColumn), columnRef(d, c.RoleTable, c.RoleScopeColumn)).
+		Joins(fmt.Sprintf("JOIN %s ON %s = %s", d.Quote(c.UserRoleTable),
+			columnRef(d, c.UserRoleTable, c.RoleIdColumn), columnRef(d, c.RoleTable, c.RoleIdColumn))).
+		Where(columnRef(d, c.UserRoleTable, c.UserIdColumn)+" = ?", userId).
+		Rows()
+	if err != nil {
+		return nil, errors.New("获取用户角色出错 msg:" + err.Error())
+	}
+	var roles []DataRole
+	for rows.Next() {
+		var role DataRole
+		if err = rows.Scan(&role.RoleId, &role.DataScope); err != nil {
+			break
+		}
+		roles = append(roles, role)
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	_ = rows.Close()
 	if err != nil {
-		err = errors.New("获取用户数据出错 msg:" + err.Error())
-		return nil, err
+		return nil, errors.New("获取用户角色出错 msg:" + err.Error())
+	}
+	for _, role := range roles {
+		role.RoleId = scanValue(role.RoleId)
+		if role.DataScope == DataScopeCustom {
+			err = tx.Table(c.RoleDeptTable).
+				Where(d.Quote(c.RoleIdColumn)+" = ?", role.RoleId).
+				Pluck(c.UserDeptColumn, &role.DeptIds).Error
+			if err != nil {
+				return nil, errors.New("获取角色部门出错 msg:" + err.Error())
+			}
+			for i, v := range role.DeptIds {
+				role.DeptIds[i] = scanValue(v)
+			}
+		}
+		p.Roles = append(p.Roles, role)
+	}
+	if len(p.Roles) > 0 {
+		p.RoleId, p.DataScope = p.Roles[0].RoleId, p.Roles[0].DataScope
 	}
 	return p, nil
 }
+
+// scanValue 部分驱动以 []byte 返回任意类型的列值，转换为字符串以便作为查询参数和比较
+func scanValue(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
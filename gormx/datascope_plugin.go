@@ -0,0 +1,242 @@
+package gormx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/qiaogw/sub-sdk/gormx/plugins"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DataScopeTag 标记数据归属字段，模型含该标签时自动启用数据权限，e.g. datascope:"owner"
+	DataScopeTag = "datascope"
+
+	dataScopeCallbackName = "gorm-zero-datascope"
+)
+
+type (
+	// DataScopeModel 实现该接口且返回 true 的模型自动启用数据权限，归属列取 DataScopePlugin.Config.OwnerColumn
+	DataScopeModel interface {
+		EnableDataScope() bool
+	}
+
+	// DataScopePlugin 数据权限插件，从 context.Context 读取 DataPermission，
+	// 对启用数据权限的模型在查询、更新、删除时自动追加数据范围条件
+	// 上下文中没有数据权限时拒绝访问，系统任务需通过 SkipDataScope 显式跳过
+	DataScopePlugin struct {
+		Config *DataScopeConfig // 为空时使用 DefaultDataScopeConfig
+	}
+
+	dataPermissionKey struct{}
+	skipDataScopeKey  struct{}
+
+	// dataScopeModelInfo 模型的数据权限配置缓存，owner 为 datascope 标签所在列
+	dataScopeModelInfo struct {
+		enabled bool
+		owner   string
+	}
+)
+
+// dataScopeModels 缓存模型是否启用数据权限，key 为 *schema.Schema
+var dataScopeModels sync.Map
+
+func init() {
+	plugins.Register("datascope", &DataScopePlugin{})
+}
+
+// WithDataPermission 将数据权限写入上下文
+func WithDataPermission(ctx context.Context, p *DataPermission) context.Context {
+	return context.WithValue(ctx, dataPermissionKey{}, p)
+}
+
+// DataPermissionFromContext 从上下文读取数据权限
+func DataPermissionFromContext(ctx context.Context) (*DataPermission, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	p, ok := ctx.Value(dataPermissionKey{}).(*DataPermission)
+	return p, ok && p != nil
+}
+
+// SkipDataScope 跳过数据权限，用于系统任务等不受用户数据范围限制的场景
+func SkipDataScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipDataScopeKey{}, true)
+}
+
+func isSkipDataScope(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	skip, _ := ctx.Value(skipDataScopeKey{}).(bool)
+	return skip
+}
+
+func (p *DataScopePlugin) Name() string {
+	return "gorm-zero-datascope-plugin"
+}
+
+func (p *DataScopePlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register(dataScopeCallbackName+":query", p.query); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register(dataScopeCallbackName+":row", p.query); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register(dataScopeCallbackName+":update", p.write); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register(dataScopeCallbackName+":delete", p.write)
+}
+
+// 告诉编译器这个结构体实现了gorm.Plugin接口
+var _ gorm.Plugin = &DataScopePlugin{}
+
+// query 查询时追加数据范围条件
+func (p *DataScopePlugin) query(db *gorm.DB) {
+	expr, ok := p.prepare(db)
+	if !ok {
+		return
+	}
+	addGroupedWhere(db.Statement, expr)
+}
+
+// write 更新、删除前检查受影响的数据是否全部在数据范围内，存在范围外数据时返回 errx.ErrAuth
+func (p *DataScopePlugin) write(db *gorm.DB) {
+	expr, ok := p.prepare(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	where := clause.Where{Exprs: append(groupedWhere(stmt), primaryKeyExprs(stmt)...)}
+	// 没有条件时交由 gorm 按 ErrMissingWhereClause 处理，避免追加的条件绕过全表更新检查
+	if len(where.Exprs) == 0 && !db.AllowGlobalUpdate {
+		return
+	}
+	var outside int64
+	err := db.Session(&gorm.Session{NewDB: true, Context: SkipDataScope(stmt.Context)}).
+		Model(reflect.New(stmt.Schema.ModelType).Interface()).
+		Table(stmt.Table).
+		Clauses(where).
+		Where(clause.Expr{SQL: fmt.Sprintf("(CASE WHEN %s THEN 1 ELSE 0 END) = 0", expr.SQL), Vars: expr.Vars}).
+		Count(&outside).Error
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	if outside > 0 {
+		_ = db.AddError(errx.NewErrCode(errx.ErrAuth))
+		return
+	}
+	addGroupedWhere(stmt, expr)
+}
+
+// prepare 生成当前语句的数据范围条件，ok 为 false 时不限制或已出错
+func (p *DataScopePlugin) prepare(db *gorm.DB) (clause.Expr, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || isSkipDataScope(stmt.Context) {
+		return clause.Expr{}, false
+	}
+	info := dataScopeModelOf(stmt.Schema)
+	if !info.enabled {
+		return clause.Expr{}, false
+	}
+	perm, ok := DataPermissionFromContext(stmt.Context)
+	if !ok {
+		_ = db.AddError(errx.NewErrCode(errx.ErrAuth))
+		return clause.Expr{}, false
+	}
+	conf := *p.config()
+	if info.owner != "" {
+		conf.OwnerColumn = info.owner
+	}
+	sql, args, all, err := conf.condition(DialectOf(db), stmt.Table, perm)
+	if err != nil {
+		_ = db.AddError(err)
+		return clause.Expr{}, false
+	}
+	if all {
+		return clause.Expr{}, false
+	}
+	return clause.Expr{SQL: sql, Vars: args}, true
+}
+
+func (p *DataScopePlugin) config() *DataScopeConfig {
+	if p.Config != nil {
+		return p.Config
+	}
+	return DefaultDataScopeConfig
+}
+
+// dataScopeModelOf 判断模型是否启用数据权限，datascope 标签优先，其次为 DataScopeModel 接口
+func dataScopeModelOf(s *schema.Schema) dataScopeModelInfo {
+	if v, ok := dataScopeModels.Load(s); ok {
+		return v.(dataScopeModelInfo)
+	}
+	info := dataScopeModelInfo{}
+	for _, f := range s.Fields {
+		if _, ok := f.Tag.Lookup(DataScopeTag); ok && f.DBName != "" {
+			info.enabled, info.owner = true, f.DBName
+			break
+		}
+	}
+	if !info.enabled {
+		if m, ok := reflect.New(s.ModelType).Interface().(DataScopeModel); ok {
+			info.enabled = m.EnableDataScope()
+		}
+	}
+	dataScopeModels.Store(s, info)
+	return info
+}
+
+// primaryKeyExprs 模型取值中的主键条件，gorm 在执行更新、删除时才追加，检查范围时需提前生成
+func primaryKeyExprs(stmt *gorm.Statement) []clause.Expression {
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil || !stmt.ReflectValue.IsValid() {
+		return nil
+	}
+	column := clause.Column{Table: stmt.Table, Name: pk.DBName}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		if v, zero := pk.ValueOf(stmt.Context, stmt.ReflectValue); !zero {
+			return []clause.Expression{clause.Eq{Column: column, Value: v}}
+		}
+	case reflect.Slice, reflect.Array:
+		values := make([]interface{}, 0, stmt.ReflectValue.Len())
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			if v, zero := pk.ValueOf(stmt.Context, reflect.Indirect(stmt.ReflectValue.Index(i))); !zero {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			return []clause.Expression{clause.IN{Column: column, Values: values}}
+		}
+	}
+	return nil
+}
+
+// groupedWhere 语句已有的 WHERE 条件合并为一组，追加的条件不受其中 OR 优先级的影响
+func groupedWhere(stmt *gorm.Statement) []clause.Expression {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return nil
+	}
+	w, ok := c.Expression.(clause.Where)
+	if !ok || len(w.Exprs) == 0 {
+		return nil
+	}
+	return []clause.Expression{clause.And(w.Exprs...)}
+}
+
+// addGroupedWhere 以括号包裹已有的 WHERE 条件后追加 exprs
+func addGroupedWhere(stmt *gorm.Statement, exprs ...clause.Expression) {
+	c := stmt.Clauses["WHERE"]
+	c.Name = "WHERE"
+	c.Expression = clause.Where{Exprs: append(groupedWhere(stmt), exprs...)}
+	stmt.Clauses["WHERE"] = c
+}
@@ -0,0 +1,67 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/stretchr/testify/assert"
+)
+
+type scopedDoc struct {
+	Id      int64
+	Title   string
+	OwnerId string `datascope:"owner"`
+}
+
+type scopedNote struct {
+	Id       int64
+	CreateBy string
+}
+
+func (scopedNote) EnableDataScope() bool { return true }
+
+func TestDataScopePlugin(t *testing.T) {
+	db := newCursorDB(t)
+	assert.Nil(t, db.Use(&DataScopePlugin{}))
+	sys := SkipDataScope(context.Background())
+	assert.Nil(t, db.AutoMigrate(&scopedDoc{}, &scopedNote{}))
+	assert.Nil(t, db.WithContext(sys).Create(&[]scopedDoc{{Title: "a", OwnerId: "u1"}, {Title: "b", OwnerId: "u2"}, {Title: "c", OwnerId: "u1"}}).Error)
+	assert.Nil(t, db.WithContext(sys).Create(&[]scopedNote{{CreateBy: "u1"}, {CreateBy: "u2"}}).Error)
+
+	isAuthErr := func(err error) bool {
+		var codeErr *errx.CodeError
+		return errors.As(err, &codeErr) && codeErr.GetErrCode() == errx.ErrAuth
+	}
+	u1 := WithDataPermission(context.Background(), &DataPermission{UserId: "u1", DataScope: DataScopeSelf})
+
+	var docs []scopedDoc
+	assert.Nil(t, db.WithContext(u1).Order("id").Find(&docs).Error)
+	assert.Len(t, docs, 2)
+	var count int64
+	assert.Nil(t, db.WithContext(u1).Model(&scopedNote{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	// 已有条件中的 OR 不影响数据范围
+	assert.Nil(t, db.WithContext(u1).Where("title = ?", "b").Or("title = ?", "c").Find(&docs).Error)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, "c", docs[0].Title)
+	// 未启用数据权限的模型不受影响
+	assert.Nil(t, db.WithContext(u1).Find(&[]searchUser{}).Error)
+
+	// 上下文中没有数据权限时拒绝访问
+	assert.True(t, isAuthErr(db.Find(&docs).Error))
+
+	// 写入范围外的数据返回 ErrAuth 且不修改任何数据
+	assert.True(t, isAuthErr(db.WithContext(u1).Model(&scopedDoc{}).Where("title IN ?", []string{"a", "b"}).Update("title", "x").Error))
+	assert.True(t, isAuthErr(db.WithContext(u1).Delete(&scopedDoc{Id: 2}).Error))
+	assert.True(t, isAuthErr(db.WithContext(u1).Model(&scopedDoc{}).Where("title = ?", "zzz").Or("title = ?", "b").Update("title", "x").Error))
+	assert.True(t, isAuthErr(db.WithContext(u1).Where("title = ?", "zzz").Or("title = ?", "b").Delete(&scopedDoc{}).Error))
+	assert.Nil(t, db.WithContext(sys).Where("title = ?", "x").Find(&docs).Error)
+	assert.Empty(t, docs)
+
+	assert.Nil(t, db.WithContext(u1).Model(&scopedDoc{Id: 1}).Update("title", "y").Error)
+	assert.Nil(t, db.WithContext(u1).Delete(&scopedDoc{}, 3).Error)
+	assert.Nil(t, db.WithContext(sys).Order("id").Find(&docs).Error)
+	assert.Equal(t, []string{"y", "b"}, []string{docs[0].Title, docs[1].Title})
+}
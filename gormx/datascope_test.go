@@ -0,0 +1,72 @@
+package gormx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionData(t *testing.T) {
+	conf := *DefaultDataScopeConfig
+	conf.UserTable, conf.DeptTable, conf.OwnerColumn = "sys_user", "sys_dept", "owner_id"
+
+	p := &DataPermission{UserId: 7, DeptId: 3, Roles: []DataRole{
+		{DataScope: DataScopeSelf},
+		{DataScope: DataScopeCustom, UserIds: []interface{}{"1' OR '1'='1"}},
+		{DataScope: DataScopeDeptAndChild},
+	}}
+	stmt := newDryRunDB(t).Scopes(conf.PermissionData("user", p)).Find(&[]searchUser{}).Statement
+	assert.Nil(t, stmt.Error)
+	assert.Equal(t, "SELECT * FROM `user` WHERE (`user`.`owner_id` = ? OR `user`.`owner_id` IN (?) OR "+
+		"`user`.`owner_id` IN (SELECT `user_id` FROM `sys_user` WHERE `dept_id` IN (WITH RECURSIVE dept_tree AS "+
+		"(SELECT `id` FROM `sys_dept` WHERE `id` = ? UNION ALL SELECT c.`id` FROM `sys_dept` c JOIN dept_tree t "+
+		"ON c.`parent_id` = t.`id`) SELECT `id` FROM dept_tree)))", stmt.SQL.String())
+	assert.Equal(t, []interface{}{7, "1' OR '1'='1", 3}, stmt.Vars)
+
+	// 任一角色为全部数据时不限制
+	p.Roles = append(p.Roles, DataRole{DataScope: DataScopeAll})
+	stmt = newDryRunDB(t).Scopes(conf.PermissionData("user", p)).Find(&[]searchUser{}).Statement
+	assert.Equal(t, "SELECT * FROM `user`", stmt.SQL.String())
+
+	RegisterDataScope("9", DataScopeFunc(func(c *DataScopeContext) (string, []interface{}, bool) {
+		return c.Column(c.Table, "tenant_id") + " = ?", []interface{}{c.Role.RoleId}, false
+	}))
+	stmt = newDryRunDB(t).Scopes(PermissionData("user", &DataPermission{DataScope: "9", RoleId: 2})).
+		Find(&[]searchUser{}).Statement
+	assert.Equal(t, "SELECT * FROM `user` WHERE `user`.`tenant_id` = ?", stmt.SQL.String())
+
+	stmt = newDryRunDB(t).Scopes(PermissionData("user", &DataPermission{DataScope: "x"})).Find(&[]searchUser{}).Statement
+	assert.NotNil(t, stmt.Error)
+
+	// 旧版 DataFilter 的用户 id 列表转为参数，其他内容返回错误
+	stmt = newDryRunDB(t).Scopes(PermissionData("user", &DataPermission{DataScope: DataScopeCustom, DataFilter: "1, 2,'u3'"})).
+		Find(&[]searchUser{}).Statement
+	assert.Nil(t, stmt.Error)
+	assert.Equal(t, "SELECT * FROM `user` WHERE `user`.`create_by` IN (?,?,?)", stmt.SQL.String())
+	assert.Equal(t, []interface{}{int64(1), int64(2), "u3"}, stmt.Vars)
+	stmt = newDryRunDB(t).Scopes(PermissionData("user", &DataPermission{DataScope: DataScopeCustom,
+		DataFilter: "SELECT user_id FROM admin_user"})).Find(&[]searchUser{}).Statement
+	assert.NotNil(t, stmt.Error)
+}
+
+func TestLoadDataPermission(t *testing.T) {
+	db := newCursorDB(t)
+	for _, sql := range []string{
+		"CREATE TABLE admin_user (user_id INTEGER, dept_id INTEGER)",
+		"CREATE TABLE admin_role (role_id INTEGER, data_scope TEXT)",
+		"CREATE TABLE admin_user_role (user_id INTEGER, role_id INTEGER)",
+		"CREATE TABLE admin_role_dept (role_id INTEGER, dept_id INTEGER)",
+		"INSERT INTO admin_user VALUES (1, 10)",
+		"INSERT INTO admin_role VALUES (1, '5'), (2, '2'), (3, '1')",
+		"INSERT INTO admin_user_role VALUES (1, 1), (1, 2)",
+		"INSERT INTO admin_role_dept VALUES (2, 20), (2, 21)",
+	} {
+		assert.Nil(t, db.Exec(sql).Error)
+	}
+	p, err := LoadDataPermission(db, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), p.DeptId)
+	assert.Len(t, p.Roles, 2)
+	assert.Equal(t, DataScopeSelf, p.Roles[0].DataScope)
+	assert.Equal(t, []interface{}{int64(20), int64(21)}, p.Roles[1].DeptIds)
+}
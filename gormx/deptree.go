@@ -0,0 +1,282 @@
+package gormx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+// 部门闭包表的列名
+const (
+	closureAncestor   = "ancestor_id"
+	closureDescendant = "descendant_id"
+	closureDepth      = "depth"
+)
+
+var (
+	// ErrDeptMoveToDescendant 部门不能移动到自身或其下级部门
+	ErrDeptMoveToDescendant = errx.NewErrCodeMsg(errx.RequestParamError, "不能将部门移动到自身或其下级部门")
+	// ErrDeptCycle 部门表的上级关系存在循环，无法重建闭包表
+	ErrDeptCycle = errx.NewErrCodeMsg(errx.ServerCommonError, "部门上级关系存在循环")
+)
+
+type (
+	// DeptClosure 部门闭包表，记录每个部门与其自身及全部上级部门的关系
+	DeptClosure[K comparable] struct {
+		AncestorId   K   `json:"ancestorId" gorm:"column:ancestor_id;primaryKey;autoIncrement:false;comment:上级部门"`
+		DescendantId K   `json:"descendantId" gorm:"column:descendant_id;primaryKey;autoIncrement:false;index:idx_dept_closure_descendant;comment:下级部门"`
+		Depth        int `json:"depth" gorm:"column:depth;comment:层级距离，0 为自身"`
+	}
+
+	// DeptTree 基于闭包表维护部门层级，查询结果通过 CachedConn 缓存
+	// 部门模型在 AfterCreate、AfterUpdate、AfterDelete 钩子中调用同名方法保持闭包表同步，
+	// e.g. func (d *Dept) AfterCreate(tx *gorm.DB) error { return deptTree.AfterCreate(tx, d.Id, d.ParentId) }
+	// 上级部门为 K 的零值时表示顶级部门
+	DeptTree[K comparable] struct {
+		conn   CachedConn
+		config *DataScopeConfig
+		table  string
+	}
+)
+
+// NewDeptTree 创建部门树，闭包表为 conf.DeptClosureTable，与数据范围查询使用同一张表；
+// conf 为空时使用 DefaultDataScopeConfig，未配置 DeptClosureTable 时为部门表名加 _closure 并写入 conf，
+// 此后该配置的本部门及以下数据改用闭包表查询
+// 钩子中的缓存在 gorm 默认事务提交后删除，外层使用事务时请通过 conn.TransactCtx 开启
+func NewDeptTree[K comparable](conn CachedConn, conf *DataScopeConfig) *DeptTree[K] {
+	if conf == nil {
+		conf = DefaultDataScopeConfig
+	}
+	if conf.DeptClosureTable == "" {
+		conf.DeptClosureTable = conf.DeptTable + "_closure"
+	}
+	table := conf.DeptClosureTable
+	if err := registerCacheFlush(conn.db); err != nil {
+		logx.Errorf("注册部门缓存删除回调出错: %v", err)
+	}
+	return &DeptTree[K]{conn: conn, config: conf, table: table}
+}
+
+// Descendants 全部下级部门，不含自身，按层级由近到远排列
+func (t *DeptTree[K]) Descendants(ctx context.Context, id K) ([]K, error) {
+	var ids []K
+	err := t.conn.QueryCtx(ctx, &ids, t.descendantsKey(id), func(conn *gorm.DB) error {
+		q := DialectOf(conn).Quote
+		return conn.Table(t.table).
+			Where(q(closureAncestor)+" = ? AND "+q(closureDepth)+" > 0", id).
+			Order(q(closureDepth)).Order(q(closureDescendant)).
+			Pluck(closureDescendant, &ids).Error
+	})
+	return ids, err
+}
+
+// Ancestors 全部上级部门，不含自身，从顶级部门到直接上级排列
+func (t *DeptTree[K]) Ancestors(ctx context.Context, id K) ([]K, error) {
+	var ids []K
+	err := t.conn.QueryCtx(ctx, &ids, t.ancestorsKey(id), func(conn *gorm.DB) error {
+		q := DialectOf(conn).Quote
+		return conn.Table(t.table).
+			Where(q(closureDescendant)+" = ? AND "+q(closureDepth)+" > 0", id).
+			Order(q(closureDepth)+" DESC").
+			Pluck(closureAncestor, &ids).Error
+	})
+	return ids, err
+}
+
+// IsDescendant 判断 id 是否为 ancestor 的下级部门，相同部门返回 false
+func (t *DeptTree[K]) IsDescendant(ctx context.Context, id, ancestor K) (bool, error) {
+	ancestors, err := t.Ancestors(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range ancestors {
+		if v == ancestor {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AfterCreate 部门创建后写入其与自身及全部上级部门的关系
+func (t *DeptTree[K]) AfterCreate(tx *gorm.DB, id, parentId K) error {
+	tx = tx.Session(&gorm.Session{NewDB: true, Context: hookContext(tx)})
+	q := DialectOf(tx).Quote
+	table, anc, desc, depth := q(t.table), q(closureAncestor), q(closureDescendant), q(closureDepth)
+	err := tx.Exec(fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (?, ?, 0)", table, anc, desc, depth), id, id).Error
+	if err != nil {
+		return err
+	}
+	var zero K
+	if parentId != zero {
+		err = tx.Exec(fmt.Sprintf("INSERT INTO %s (%s, %s, %s) SELECT %s, ?, %s + 1 FROM %s WHERE %s = ?",
+			table, anc, desc, depth, anc, depth, table, desc), id, parentId).Error
+		if err != nil {
+			return err
+		}
+	}
+	ancestors, err := t.pluck(tx, closureAncestor, closureDescendant, id)
+	if err != nil {
+		return err
+	}
+	return t.delCache(tx, ancestors, []K{id})
+}
+
+// AfterUpdate 部门更新后按新的上级部门调整其子树的关系，上级未变化时不做处理
+// 部门不在闭包表中时按新建处理；不能移动到自身或其下级部门
+func (t *DeptTree[K]) AfterUpdate(tx *gorm.DB, id, parentId K) error {
+	tx = tx.Session(&gorm.Session{NewDB: true, Context: hookContext(tx)})
+	q := DialectOf(tx).Quote
+	var rows []DeptClosure[K]
+	if err := tx.Table(t.table).Where(q(closureDescendant)+" = ?", id).Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return t.AfterCreate(tx, id, parentId)
+	}
+	var zero K
+	oldParent, oldAncestors := zero, make([]K, 0, len(rows))
+	for _, r := range rows {
+		if r.Depth == 1 {
+			oldParent = r.AncestorId
+		}
+		if r.Depth > 0 {
+			oldAncestors = append(oldAncestors, r.AncestorId)
+		}
+	}
+	if oldParent == parentId {
+		return nil
+	}
+	subtree, err := t.pluck(tx, closureDescendant, closureAncestor, id)
+	if err != nil {
+		return err
+	}
+	for _, v := range subtree {
+		if v == parentId {
+			return ErrDeptMoveToDescendant
+		}
+	}
+	table, anc, desc, depth := q(t.table), q(closureAncestor), q(closureDescendant), q(closureDepth)
+	// 先断开子树与原上级的关系，再与新上级的全部上级逐一关联
+	if len(oldAncestors) > 0 {
+		err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s IN ? AND %s IN ?", table, desc, anc), subtree, oldAncestors).Error
+		if err != nil {
+			return err
+		}
+	}
+	var newAncestors []K
+	if parentId != zero {
+		err = tx.Exec(fmt.Sprintf("INSERT INTO %s (%s, %s, %s) SELECT p.%s, s.%s, p.%s + s.%s + 1 FROM %s p, %s s WHERE p.%s = ? AND s.%s = ?",
+			table, anc, desc, depth, anc, desc, depth, depth, table, table, desc, anc), parentId, id).Error
+		if err != nil {
+			return err
+		}
+		if newAncestors, err = t.pluck(tx, closureAncestor, closureDescendant, parentId); err != nil {
+			return err
+		}
+	}
+	return t.delCache(tx, append(oldAncestors, newAncestors...), subtree)
+}
+
+// AfterDelete 部门删除后移除其关系，其下级部门与原上级部门断开
+func (t *DeptTree[K]) AfterDelete(tx *gorm.DB, id K) error {
+	tx = tx.Session(&gorm.Session{NewDB: true, Context: hookContext(tx)})
+	q := DialectOf(tx).Quote
+	ancestors, err := t.pluck(tx, closureAncestor, closureDescendant, id)
+	if err != nil {
+		return err
+	}
+	subtree, err := t.pluck(tx, closureDescendant, closureAncestor, id)
+	if err != nil {
+		return err
+	}
+	table, anc, desc := q(t.table), q(closureAncestor), q(closureDescendant)
+	if len(subtree) > 0 && len(ancestors) > 0 {
+		err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s IN ? AND %s IN ?", table, desc, anc), subtree, ancestors).Error
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ? OR %s = ?", table, anc, desc), id, id).Error
+	if err != nil {
+		return err
+	}
+	return t.delCache(tx, append(ancestors, id), append(subtree, id))
+}
+
+// Rebuild 按部门表的上级关系重建闭包表，闭包表不存在时自动创建
+// 用于初始化或修复闭包表，e.g. 部门数据通过 SQL 直接导入后执行一次
+func (t *DeptTree[K]) Rebuild(ctx context.Context) error {
+	conf := t.config
+	var ids []K
+	if err := t.conn.db.WithContext(ctx).Table(t.table).AutoMigrate(&DeptClosure[K]{}); err != nil {
+		return err
+	}
+	err := t.conn.TransactCtx(ctx, func(tx *gorm.DB) error {
+		q := DialectOf(tx).Quote
+		table, anc, desc, depth := q(t.table), q(closureAncestor), q(closureDescendant), q(closureDepth)
+		dept, deptId := q(conf.DeptTable), q(conf.DeptIdColumn)
+		if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(conf.DeptTable).Pluck(conf.DeptIdColumn, &ids).Error; err != nil {
+			return err
+		}
+		err := tx.Exec(fmt.Sprintf("INSERT INTO %s (%s, %s, %s) SELECT %s, %s, 0 FROM %s",
+			table, anc, desc, depth, deptId, deptId, dept)).Error
+		if err != nil {
+			return err
+		}
+		// 逐层向下扩展：上级部门的每条关系与其直接下级组合为距离加一的关系
+		expand := fmt.Sprintf("INSERT INTO %s (%s, %s, %s) SELECT c.%s, d.%s, c.%s + 1 FROM %s c JOIN %s d ON d.%s = c.%s WHERE c.%s = ?",
+			table, anc, desc, depth, anc, deptId, depth, table, dept, q(conf.DeptParentColumn), desc, depth)
+		for level := 0; ; level++ {
+			if level > len(ids) {
+				return ErrDeptCycle
+			}
+			res := tx.Exec(expand, level)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return nil
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return t.delCache(t.conn.db.WithContext(ctx), ids, ids)
+}
+
+// pluck 查询闭包表中 where 列等于 id 的 column 列，含自身
+func (t *DeptTree[K]) pluck(tx *gorm.DB, column, where string, id K) ([]K, error) {
+	var ids []K
+	err := tx.Table(t.table).Where(DialectOf(tx).Quote(where)+" = ?", id).Pluck(column, &ids).Error
+	return ids, err
+}
+
+// delCache 删除 descendants 中部门的下级缓存及 ancestors 中部门的上级缓存
+func (t *DeptTree[K]) delCache(tx *gorm.DB, descendants, ancestors []K) error {
+	keys := make([]string, 0, len(descendants)+len(ancestors))
+	for _, id := range descendants {
+		keys = append(keys, t.descendantsKey(id))
+	}
+	for _, id := range ancestors {
+		keys = append(keys, t.ancestorsKey(id))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return t.conn.DelCacheCtx(tx.Statement.Context, keys...)
+}
+
+func (t *DeptTree[K]) descendantsKey(id K) string {
+	return fmt.Sprintf("cache:%s:descendants:%v", t.table, id)
+}
+
+func (t *DeptTree[K]) ancestorsKey(id K) string {
+	return fmt.Sprintf("cache:%s:ancestors:%v", t.table, id)
+}
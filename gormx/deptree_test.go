@@ -0,0 +1,187 @@
+package gormx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// memCache 测试用的内存缓存
+type memCache struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func newMemCache() *memCache {
+	return &memCache{data: map[string]string{}}
+}
+
+func (c *memCache) Del(keys ...string) error { return c.DelCtx(context.Background(), keys...) }
+
+func (c *memCache) DelCtx(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.data, key)
+	}
+	return nil
+}
+
+func (c *memCache) Get(key string, val any) error { return c.GetCtx(context.Background(), key, val) }
+
+func (c *memCache) GetCtx(_ context.Context, key string, val any) error {
+	c.mu.Lock()
+	s, ok := c.data[key]
+	c.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	return json.Unmarshal([]byte(s), val)
+}
+
+func (c *memCache) IsNotFound(err error) bool { return errors.Is(err, ErrNotFound) }
+
+func (c *memCache) Set(key string, val any) error { return c.SetCtx(context.Background(), key, val) }
+
+func (c *memCache) SetCtx(ctx context.Context, key string, val any) error {
+	return c.SetWithExpireCtx(ctx, key, val, time.Hour)
+}
+
+func (c *memCache) SetWithExpire(key string, val any, expire time.Duration) error {
+	return c.SetWithExpireCtx(context.Background(), key, val, expire)
+}
+
+func (c *memCache) SetWithExpireCtx(_ context.Context, key string, val any, _ time.Duration) error {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = string(b)
+	return nil
+}
+
+func (c *memCache) Take(val any, key string, query func(val any) error) error {
+	return c.TakeCtx(context.Background(), val, key, query)
+}
+
+func (c *memCache) TakeCtx(ctx context.Context, val any, key string, query func(val any) error) error {
+	return c.TakeWithExpireCtx(ctx, val, key, func(val any, _ time.Duration) error { return query(val) })
+}
+
+func (c *memCache) TakeWithExpire(val any, key string, query func(val any, expire time.Duration) error) error {
+	return c.TakeWithExpireCtx(context.Background(), val, key, query)
+}
+
+func (c *memCache) TakeWithExpireCtx(ctx context.Context, val any, key string, query func(val any, expire time.Duration) error) error {
+	if err := c.GetCtx(ctx, key, val); !c.IsNotFound(err) {
+		return err
+	}
+	if err := query(val, time.Hour); err != nil {
+		return err
+	}
+	return c.SetCtx(ctx, key, val)
+}
+
+func (c *memCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.data[key]
+	return ok
+}
+
+// treeDept 测试用的部门模型，通过钩子维护闭包表
+type treeDept struct {
+	Id       int64 `gorm:"primaryKey"`
+	ParentId int64
+	tree     *DeptTree[int64] `gorm:"-"`
+	fail     bool             `gorm:"-"`
+}
+
+func (treeDept) TableName() string { return "admin_dept" }
+
+func (d *treeDept) AfterCreate(tx *gorm.DB) error {
+	if err := d.tree.AfterCreate(tx, d.Id, d.ParentId); err != nil {
+		return err
+	}
+	if d.fail {
+		return errors.New("rollback")
+	}
+	return nil
+}
+
+func (d *treeDept) AfterUpdate(tx *gorm.DB) error { return d.tree.AfterUpdate(tx, d.Id, d.ParentId) }
+
+func (d *treeDept) AfterDelete(tx *gorm.DB) error { return d.tree.AfterDelete(tx, d.Id) }
+
+func TestDeptTree(t *testing.T) {
+	ctx := context.Background()
+	db := newCursorDB(t)
+	mc := newMemCache()
+	conf := *DefaultDataScopeConfig
+	tree := NewDeptTree[int64](NewConnWithCache(db, mc), &conf)
+	assert.Nil(t, db.AutoMigrate(&treeDept{}))
+	// 已有部门数据时通过重建初始化闭包表：1 -> 2 -> 3，1 -> 4
+	assert.Nil(t, db.Exec("INSERT INTO admin_dept (id, parent_id) VALUES (1, 0), (2, 1), (3, 2), (4, 1)").Error)
+	assert.Nil(t, tree.Rebuild(ctx))
+
+	ids, err := tree.Descendants(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{2, 4, 3}, ids)
+	assert.True(t, mc.has(tree.descendantsKey(1)))
+	ids, err = tree.Ancestors(ctx, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+
+	// 事务回滚时不删除缓存，提交后才删除
+	assert.NotNil(t, db.Create(&treeDept{Id: 6, ParentId: 3, tree: tree, fail: true}).Error)
+	assert.True(t, mc.has(tree.descendantsKey(1)))
+
+	// 新建部门 5 挂在 3 下，上级部门的缓存失效
+	assert.Nil(t, db.Create(&treeDept{Id: 5, ParentId: 3, tree: tree}).Error)
+	assert.False(t, mc.has(tree.descendantsKey(1)))
+	ids, err = tree.Descendants(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{2, 4, 3, 5}, ids)
+
+	// 将 3 移动到 4 下，子树 5 随之移动
+	assert.Nil(t, db.Save(&treeDept{Id: 3, ParentId: 4, tree: tree}).Error)
+	ids, err = tree.Ancestors(ctx, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 4, 3}, ids)
+	ok, err := tree.IsDescendant(ctx, 5, 2)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ok, err = tree.IsDescendant(ctx, 5, 4)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// 不能移动到自身的下级部门
+	err = db.Save(&treeDept{Id: 4, ParentId: 5, tree: tree}).Error
+	assert.True(t, errors.Is(err, ErrDeptMoveToDescendant))
+
+	// 删除 3，下级部门 5 与原上级断开
+	assert.Nil(t, db.Delete(&treeDept{Id: 3, tree: tree}).Error)
+	ids, err = tree.Descendants(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{2, 4}, ids)
+	ids, err = tree.Ancestors(ctx, 5)
+	assert.Nil(t, err)
+	assert.Empty(t, ids)
+
+	// 数据范围使用同一闭包表，默认配置不受影响，仍使用递归查询
+	assert.Equal(t, "admin_dept_closure", conf.DeptClosureTable)
+	assert.Equal(t, conf.DeptClosureTable, tree.table)
+	assert.Empty(t, DefaultDataScopeConfig.DeptClosureTable)
+	stmt := newDryRunDB(t).Scopes(conf.PermissionData("user", &DataPermission{DataScope: DataScopeDeptAndChild, DeptId: 1})).
+		Find(&[]searchUser{}).Statement
+	assert.Equal(t, "SELECT * FROM `user` WHERE `user`.`create_by` IN (SELECT `user_id` FROM `admin_user` WHERE `dept_id` "+
+		"IN (SELECT `descendant_id` FROM `admin_dept_closure` WHERE `ancestor_id` = ?))", stmt.SQL.String())
+}
@@ -0,0 +1,204 @@
+package gormx
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// SearchDialect 检索 SQL 方言，负责标识符引用、LIKE 与 JOIN 语句的生成
+// 内置 mysql、postgres、sqlite，其它数据库（如达梦、人大金仓）可通过 RegisterSearchDialect 注册
+type SearchDialect interface {
+	// Name 方言族名称（mysql/postgres/sqlite），JSON、数组等运算符按此选择语法
+	Name() string
+	// Quote 引用标识符，含 "." 时逐段引用
+	Quote(name string) string
+	// Like 生成 LIKE 条件，insensitive 为 true 时不区分大小写
+	Like(columnRef string, insensitive bool) string
+	// EscapeLike 转义用户输入中的 LIKE 通配符 % 和 _
+	EscapeLike(s string) string
+	// Join 生成 JOIN 语句，kind 为 LEFT/INNER/RIGHT，alias 为空时不设置别名，on 为已引用好的条件，以 AND 连接
+	Join(kind, table, alias string, on []string) string
+	// OrderBy 生成排序片段，nulls 为 NullsFirst/NullsLast，为空时使用数据库默认顺序
+	OrderBy(columnRef string, desc bool, nulls string) string
+}
+
+var (
+	searchDialectsMu sync.RWMutex
+	searchDialects   = map[string]SearchDialect{
+		Mysql:    MysqlDialect{},
+		Postgres: PostgresDialect{},
+		Sqlite:   SqliteDialect{},
+	}
+)
+
+// RegisterSearchDialect 注册检索方言，name 对应 gorm Dialector.Name()
+// e.g. RegisterSearchDialect("kingbase", gormx.PostgresDialect{})
+func RegisterSearchDialect(name string, d SearchDialect) {
+	searchDialectsMu.Lock()
+	searchDialects[name] = d
+	searchDialectsMu.Unlock()
+	// 清理该方言下已缓存的检索计划
+	searchPlans.Range(func(key, _ any) bool {
+		if key.(planKey).dialect == name {
+			searchPlans.Delete(key)
+		}
+		return true
+	})
+}
+
+// LookupSearchDialect 按名称查找已注册的检索方言
+func LookupSearchDialect(name string) (SearchDialect, bool) {
+	searchDialectsMu.RLock()
+	defer searchDialectsMu.RUnlock()
+	d, ok := searchDialects[name]
+	return d, ok
+}
+
+// DialectOf 根据 db.Dialector 选择检索方言，未注册时按 MySQL 处理
+func DialectOf(db *gorm.DB) SearchDialect {
+	_, d := resolveDialect(dialectorName(db))
+	return d
+}
+
+// resolveDialect 返回实际使用的方言名称及方言，未注册时回退为 MySQL
+func resolveDialect(names ...string) (string, SearchDialect) {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if d, ok := LookupSearchDialect(name); ok {
+			return name, d
+		}
+	}
+	return Mysql, MysqlDialect{}
+}
+
+func dialectorName(db *gorm.DB) string {
+	if db == nil || db.Dialector == nil {
+		return ""
+	}
+	return db.Dialector.Name()
+}
+
+// quoteWith 按 "." 分段并以 open/close 引用
+func quoteWith(name, open, close string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		p = strings.ReplaceAll(p, close, close+close)
+		parts[i] = open + p + close
+	}
+	return strings.Join(parts, ".")
+}
+
+// escapeLike 以反斜杠转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// joinSQL 生成通用 JOIN 语句
+func joinSQL(d SearchDialect, kind, table, alias string, on []string) string {
+	target := d.Quote(table)
+	if alias != "" && alias != table {
+		target += " AS " + d.Quote(alias)
+	}
+	return fmt.Sprintf("%s JOIN %s ON %s", strings.ToUpper(kind), target, strings.Join(on, " AND "))
+}
+
+// orderBy 生成标准 SQL 排序片段，支持 NULLS FIRST/LAST
+func orderBy(columnRef string, desc bool, nulls string) string {
+	if desc {
+		columnRef += " DESC"
+	}
+	switch nulls {
+	case NullsFirst:
+		columnRef += " NULLS FIRST"
+	case NullsLast:
+		columnRef += " NULLS LAST"
+	}
+	return columnRef
+}
+
+// MysqlDialect MySQL 方言，反引号引用，LIKE 默认以反斜杠转义
+type MysqlDialect struct{}
+
+func (MysqlDialect) Name() string { return Mysql }
+
+func (MysqlDialect) Quote(name string) string { return quoteWith(name, "`", "`") }
+
+func (MysqlDialect) Like(columnRef string, insensitive bool) string {
+	if insensitive {
+		return fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", columnRef)
+	}
+	return fmt.Sprintf("%s LIKE ?", columnRef)
+}
+
+func (MysqlDialect) EscapeLike(s string) string { return escapeLike(s) }
+
+func (d MysqlDialect) Join(kind, table, alias string, on []string) string {
+	return joinSQL(d, kind, table, alias, on)
+}
+
+// OrderBy MySQL 不支持 NULLS FIRST/LAST，以 IS NULL 排序模拟
+func (MysqlDialect) OrderBy(columnRef string, desc bool, nulls string) string {
+	switch nulls {
+	case NullsFirst:
+		return fmt.Sprintf("%s IS NULL DESC, %s", columnRef, orderBy(columnRef, desc, ""))
+	case NullsLast:
+		return fmt.Sprintf("%s IS NULL, %s", columnRef, orderBy(columnRef, desc, ""))
+	}
+	return orderBy(columnRef, desc, "")
+}
+
+// PostgresDialect PostgreSQL 方言，双引号引用，ILIKE 不区分大小写
+type PostgresDialect struct{}
+
+func (PostgresDialect) Name() string { return Postgres }
+
+func (PostgresDialect) Quote(name string) string { return quoteWith(name, `"`, `"`) }
+
+func (PostgresDialect) Like(columnRef string, insensitive bool) string {
+	if insensitive {
+		return fmt.Sprintf("%s ILIKE ?", columnRef)
+	}
+	return fmt.Sprintf("%s LIKE ?", columnRef)
+}
+
+func (PostgresDialect) EscapeLike(s string) string { return escapeLike(s) }
+
+func (d PostgresDialect) Join(kind, table, alias string, on []string) string {
+	return joinSQL(d, kind, table, alias, on)
+}
+
+func (PostgresDialect) OrderBy(columnRef string, desc bool, nulls string) string {
+	return orderBy(columnRef, desc, nulls)
+}
+
+// SqliteDialect SQLite 方言，LIKE 对 ASCII 默认不区分大小写，需显式声明 ESCAPE
+type SqliteDialect struct{}
+
+func (SqliteDialect) Name() string { return Sqlite }
+
+func (SqliteDialect) Quote(name string) string { return quoteWith(name, "`", "`") }
+
+// Like insensitive 为 true 时两侧取 LOWER，不受 PRAGMA case_sensitive_like 影响；
+// SQLite 内置的 LOWER 与 LIKE 只处理 ASCII 字母，非 ASCII 文本需加载 ICU 扩展才能不区分大小写。
+// insensitive 为 false 时由 PRAGMA case_sensitive_like 决定，默认对 ASCII 不区分大小写
+func (SqliteDialect) Like(columnRef string, insensitive bool) string {
+	if insensitive {
+		return fmt.Sprintf(`LOWER(%s) LIKE LOWER(?) ESCAPE '\'`, columnRef)
+	}
+	return fmt.Sprintf(`%s LIKE ? ESCAPE '\'`, columnRef)
+}
+
+func (SqliteDialect) EscapeLike(s string) string { return escapeLike(s) }
+
+func (d SqliteDialect) Join(kind, table, alias string, on []string) string {
+	return joinSQL(d, kind, table, alias, on)
+}
+
+func (SqliteDialect) OrderBy(columnRef string, desc bool, nulls string) string {
+	return orderBy(columnRef, desc, nulls)
+}
@@ -0,0 +1,320 @@
+package gormx
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"gorm.io/gorm"
+)
+
+// Filter 前端传入的过滤条件，Field 为 search 字段的 json 名称，关联字段以 "." 分隔，e.g. dept.name
+type Filter struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// filterTimeLayouts 时间类型字段支持的取值格式
+var filterTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// ParseFilters 解析 field:op:value 形式的过滤表达式
+// e.g. ?filter=name:icontains:abc&filter=createdAt:between:2024-01-01,2024-02-01
+// isnull/notnull 可省略取值；in/notin/between 等多值以 "," 分隔
+func ParseFilters(exprs []string) ([]Filter, error) {
+	filters := make([]Filter, 0, len(exprs))
+	for _, expr := range exprs {
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+		parts := strings.SplitN(expr, ":", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errx.NewErrorf(errx.RequestParamError, "过滤条件格式错误: %s", expr)
+		}
+		f := Filter{Field: parts[0], Op: strings.ToLower(parts[1])}
+		if len(parts) == 3 {
+			f.Value = parts[2]
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// ParseFilterJSON 解析 JSON 形式的过滤条件
+// e.g. [{"field":"name","op":"icontains","value":"abc"},{"field":"status","op":"in","value":[1,2]}]
+func ParseFilterJSON(data []byte) ([]Filter, error) {
+	var filters []Filter
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// 保留数字原文，避免大整数转为 float64 丢失精度
+	decoder.UseNumber()
+	if err := decoder.Decode(&filters); err != nil {
+		return nil, errx.NewErrorf(errx.RequestParamError, "过滤条件格式错误: %v", err)
+	}
+	for i := range filters {
+		filters[i].Op = strings.ToLower(filters[i].Op)
+	}
+	return filters, nil
+}
+
+// ResolveFilters 按 model 的 search 标签校验过滤条件并写入 condition
+// 仅允许 search 标签中声明了 type 的字段，操作符限定为 type 及 ops 中列出的值；取值按字段类型转换
+func ResolveFilters(driver string, model interface{}, filters []Filter, condition Condition) error {
+	name, d := resolveDialect(driver)
+	return resolveFilters(name, d, model, filters, condition)
+}
+
+// MakeFilterCondition 根据前端过滤条件生成GORM查询条件，校验失败时通过 db.AddError 返回
+func MakeFilterCondition(model interface{}, filters []Filter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		condition := &GormCondition{
+			GormPublic: GormPublic{},
+			Join:       make([]*GormJoin, 0),
+		}
+		name, d := resolveDialect(dialectorName(db))
+		if err := resolveFilters(name, d, model, filters, condition); err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		return applyCondition(db, condition)
+	}
+}
+
+func resolveFilters(name string, d SearchDialect, model interface{}, filters []Filter, condition Condition) error {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return fmt.Errorf("search 查询对象必须为结构体: %v", typ)
+	}
+	plan, err := loadSearchPlan(name, d, typ)
+	if err != nil {
+		return err
+	}
+	// 同一关联只生成一次 JOIN
+	joins := make(map[*searchField]Condition)
+	for _, filter := range filters {
+		field, path := plan.lookup(strings.Split(filter.Field, "."))
+		if field == nil {
+			return errx.NewErrorf(errx.RequestParamError, "不支持的过滤字段: %s", filter.Field)
+		}
+		if !field.allowOp(filter.Op) {
+			return errx.NewErrorf(errx.RequestParamError, "字段 %s 不支持的过滤方式: %s", filter.Field, filter.Op)
+		}
+		value, err := coerceFilterValue(filter, field.typ)
+		if err != nil {
+			return err
+		}
+		t := *field.tag
+		t.Type = filter.Op
+		build, err := compileSearchExpr(d, &t, field.column)
+		if err != nil {
+			return errx.NewErrorf(errx.RequestParamError, "字段 %s 不支持的过滤方式: %s", filter.Field, filter.Op)
+		}
+		expr, args, ok := build(value)
+		if !ok {
+			return errx.NewErrorf(errx.RequestParamError, "字段 %s 的过滤值无效", filter.Field)
+		}
+		target := condition
+		for _, j := range path {
+			next, ok := joins[j]
+			if !ok {
+				next = target.SetJoinOn(j.tag.Type, j.join)
+				joins[j] = next
+			}
+			target = next
+		}
+		target.SetWhere(expr, args)
+	}
+	return nil
+}
+
+// lookup 按 json 名称路径查找字段，返回字段及途经的关联字段
+func (p *searchPlan) lookup(path []string) (*searchField, []*searchField) {
+	for _, f := range p.fields {
+		if f.json != path[0] {
+			continue
+		}
+		if f.sub == nil && len(path) == 1 {
+			return f, nil
+		}
+		if f.sub != nil && len(path) > 1 {
+			if field, joins := f.sub.lookup(path[1:]); field != nil {
+				return field, append([]*searchField{f}, joins...)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// allowOp 判断过滤操作符是否在 search 标签中声明
+func (f *searchField) allowOp(op string) bool {
+	if op == "" || op == Order {
+		return false
+	}
+	if op == f.tag.Type {
+		return true
+	}
+	for _, o := range f.tag.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// coerceFilterValue 按字段类型转换过滤取值，多值操作符返回切片
+func coerceFilterValue(filter Filter, typ reflect.Type) (reflect.Value, error) {
+	switch filter.Op {
+	case IsNull, NotNull:
+		return reflect.ValueOf(true), nil
+	}
+	tokens := filterTokens(filter)
+	elem := filterScalarType(typ)
+	switch filter.Op {
+	case In, NotIn, Between, ArrayContains, ArrayOverlap:
+		if filter.Op == Between && len(tokens) != 2 {
+			return reflect.Value{}, errx.NewErrorf(errx.RequestParamError, "字段 %s 区间取值须为两个值", filter.Field)
+		}
+		if len(tokens) == 0 {
+			return reflect.Value{}, errx.NewErrorf(errx.RequestParamError, "字段 %s 的过滤值为空", filter.Field)
+		}
+		list := reflect.MakeSlice(reflect.SliceOf(elem), 0, len(tokens))
+		for _, token := range tokens {
+			v, err := coerceScalar(token, elem)
+			if err != nil {
+				return reflect.Value{}, errx.NewErrorf(errx.RequestParamError, "字段 %s 的过滤值无效: %s", filter.Field, token)
+			}
+			list = reflect.Append(list, v)
+		}
+		return list, nil
+	}
+	if len(tokens) != 1 {
+		return reflect.Value{}, errx.NewErrorf(errx.RequestParamError, "字段 %s 的过滤值无效", filter.Field)
+	}
+	if filter.Op == JsonEqual || filter.Op == JsonContains {
+		// JSON 字段的取值由数据库比较，不按字段类型转换
+		return reflect.ValueOf(tokens[0]), nil
+	}
+	v, err := coerceScalar(tokens[0], elem)
+	if err != nil {
+		return reflect.Value{}, errx.NewErrorf(errx.RequestParamError, "字段 %s 的过滤值无效: %s", filter.Field, tokens[0])
+	}
+	return v, nil
+}
+
+// filterTokens 将过滤取值统一为字符串列表，多值操作符的字符串取值以 "," 分隔
+func filterTokens(filter Filter) []string {
+	switch v := filter.Value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		tokens := make([]string, len(v))
+		for i, item := range v {
+			tokens[i] = fmt.Sprint(item)
+		}
+		return tokens
+	case string:
+		switch filter.Op {
+		case In, NotIn, Between, ArrayContains, ArrayOverlap:
+			return strings.Split(v, ",")
+		}
+		return []string{v}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// filterScalarType 字段的单值类型：切片取元素类型，Range 取 From 类型
+func filterScalarType(typ reflect.Type) reflect.Type {
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() != reflect.Uint8 {
+			return typ.Elem()
+		}
+	case reflect.Struct:
+		if from, ok := typ.FieldByName("From"); ok {
+			if _, ok := typ.FieldByName("To"); ok {
+				return from.Type
+			}
+		}
+	}
+	return typ
+}
+
+// coerceScalar 将字符串转换为指定类型，空字符串返回零值
+func coerceScalar(s string, typ reflect.Type) (reflect.Value, error) {
+	if typ.Kind() == reflect.Ptr {
+		v, err := coerceScalar(s, typ.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(v)
+		return ptr, nil
+	}
+	v := reflect.New(typ).Elem()
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return v, nil
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		for _, layout := range filterTimeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return v, nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("invalid time %q", s)
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return v, u.UnmarshalText([]byte(s))
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if typ.Elem().Kind() != reflect.Uint8 {
+			return reflect.Value{}, fmt.Errorf("unsupported type %v", typ)
+		}
+		v.SetBytes([]byte(s))
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported type %v", typ)
+	}
+	return v, nil
+}
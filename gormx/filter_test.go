@@ -0,0 +1,68 @@
+package gormx
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/stretchr/testify/assert"
+)
+
+type filterDept struct {
+	Name string `json:"name" search:"type:exact;table:dept;column:name"`
+}
+
+type filterQuery struct {
+	Name      string           `json:"name" search:"type:contains;table:user;column:name;ops:icontains|startswith"`
+	Status    []int64          `json:"status" search:"type:in;table:user;column:status;ops:exact"`
+	CreatedAt Range[time.Time] `json:"createdAt" search:"type:between;table:user;column:created_at"`
+	Dept      filterDept       `json:"dept" search:"type:left;table:user;join:dept;on:id:dept_id"`
+	Remark    string           `json:"remark" search:"column:remark"`
+}
+
+func TestResolveFilters(t *testing.T) {
+	filters, err := ParseFilters([]string{
+		"name:icontains:abc",
+		"status:in:1,2",
+		"createdAt:between:2024-01-01,2024-02-01 10:00:00",
+		"dept.name:exact:dev",
+	})
+	assert.Nil(t, err)
+
+	condition := &GormCondition{}
+	err = ResolveFilters(Postgres, filterQuery{}, filters, condition)
+	assert.Nil(t, err)
+	assert.Equal(t, `"user"."name" ILIKE ?`, condition.Where[0].Query)
+	assert.Equal(t, []interface{}{"%abc%"}, condition.Where[0].Args)
+	assert.Equal(t, []interface{}{[]int64{1, 2}}, condition.Where[1].Args)
+	assert.Equal(t, `"user"."created_at" BETWEEN ? AND ?`, condition.Where[2].Query)
+	assert.Equal(t, time.Date(2024, 2, 1, 10, 0, 0, 0, time.Local), condition.Where[2].Args[1])
+	assert.Len(t, condition.Join, 1)
+	assert.Equal(t, `"dept"."name" = ?`, condition.Join[0].Where[0].Query)
+}
+
+func TestResolveFiltersWhitelist(t *testing.T) {
+	cases := []string{
+		"password:exact:1",
+		"name:endswith:a",
+		"remark:exact:a",
+		"status:in:a",
+	}
+	for _, c := range cases {
+		filters, err := ParseFilters([]string{c})
+		assert.Nil(t, err)
+		err = ResolveFilters(Mysql, filterQuery{}, filters, &GormCondition{})
+		var codeErr *errx.CodeError
+		assert.True(t, errors.As(err, &codeErr), c)
+		assert.Equal(t, errx.RequestParamError, codeErr.GetErrCode())
+	}
+}
+
+func TestParseFilterJSON(t *testing.T) {
+	filters, err := ParseFilterJSON([]byte(`[{"field":"status","op":"IN","value":[1,9007199254740993]}]`))
+	assert.Nil(t, err)
+	condition := &GormCondition{}
+	assert.Nil(t, ResolveFilters(Mysql, filterQuery{}, filters, condition))
+	assert.Equal(t, []interface{}{[]int64{1, 9007199254740993}}, condition.Where[0].Args)
+}
@@ -2,6 +2,7 @@ package gen
 
 import (
 	"github.com/google/uuid"
+	"github.com/qiaogw/sub-sdk/gormx/configx"
 	"github.com/qiaogw/sub-sdk/gormx/modelx"
 )
 
@@ -89,3 +90,29 @@ type Database struct {
 
 	Tables []*Table `json:"tables" gorm:"foreignKey:DatabaseId"`
 }
+
+// DataSourceName 数据源名称，用于 DataSources 复用连接池
+func (d *Database) DataSourceName() string {
+	if d.Id != uuid.Nil {
+		return d.Id.String()
+	}
+	return d.Driver + "|" + d.Host + "|" + d.Dbname
+}
+
+// DbConf 数据源的连接配置
+func (d *Database) DbConf() configx.DbConf {
+	return configx.DbConf{
+		Driver:       d.Driver,
+		Host:         d.Host,
+		Port:         d.Port,
+		Dbname:       d.Dbname,
+		Username:     d.Username,
+		Password:     d.Password,
+		Config:       d.Config,
+		Schema:       d.Schema,
+		TablePrefix:  d.TablePrefix,
+		MaxIdleConns: 2,
+		MaxOpenConns: 10,
+		LogMode:      "silent",
+	}
+}
@@ -1,6 +1,8 @@
 package gen
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 	"github.com/qiaogw/sub-sdk/errx"
 	"github.com/qiaogw/sub-sdk/gormx/configx"
@@ -20,6 +22,7 @@ type AutoCodeService struct {
 	overwrite bool   //是否覆盖
 	Database  *Database
 	oneMode   bool
+	release   func()
 }
 
 func NewAutoCodeServiceByDB(tx *gorm.DB) (*AutoCodeService, error) {
@@ -41,38 +44,34 @@ func NewAutoCodeServiceByDB(tx *gorm.DB) (*AutoCodeService, error) {
 	return acd, nil
 }
 
-func NewAutoCodeService(db *Database, one ...bool) (*AutoCodeService, error) {
-	acd := AutoCodeService{}
-	switch db.Driver {
-	case string(configx.MySQL):
-		acd.DB = new(Mysql)
-	case string(configx.Sqlite):
-		acd.DB = new(Postgres)
-	default:
-		acd.DB = new(Mysql)
-	}
-	dbConfig := configx.DbConf{
-		Driver:      db.Driver,
-		Host:        db.Host,
-		Port:        db.Port,
-		Config:      db.Config,
-		Username:    db.Username,
-		Password:    db.Password,
-		Dbname:      db.Dbname,
-		TablePrefix: db.TablePrefix,
-	}
+// DataSources 代码生成使用的数据源连接池，按 Database.Id 复用，空闲 10 分钟后关闭
+var DataSources = configx.NewDataSourceManager(10 * time.Minute)
 
-	tx, err := configx.GetConnect(dbConfig)
+// NewAutoCodeService 从 DataSources 取出数据源的连接，使用完后调用 Close 释放
+func NewAutoCodeService(db *Database, one ...bool) (*AutoCodeService, error) {
+	tx, release, err := DataSources.OpenAcquire(db.DataSourceName(), db.DbConf())
 	if err != nil {
 		return nil, errors.Wrapf(errx.NewErrCode(errx.DbError),
 			"该数据源连接失败:%v", err)
 	}
+	acd, err := NewAutoCodeServiceByDB(tx)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	acd.release = release
 	acd.mode = db.Mode
 	acd.overwrite = true //覆盖
-	acd.DB.Init(tx)
 	acd.Database = db
 	if len(one) > 0 && one[0] {
 		acd.oneMode = one[0]
 	}
-	return &acd, nil
+	return acd, nil
+}
+
+// Close 释放 NewAutoCodeService 取出的数据源连接，连接池仍由 DataSources 管理
+func (acd *AutoCodeService) Close() {
+	if acd.release != nil {
+		acd.release()
+	}
 }
@@ -0,0 +1,78 @@
+package gormx
+
+import "strings"
+
+// searchGroup 由 search 标签 group 聚合出的括号条件块
+type searchGroup struct {
+	name     string
+	logic    string
+	exprs    []string
+	args     []interface{}
+	children []*searchGroup
+}
+
+// searchGroups 保存同一层级（主表或某个关联表）下的所有分组，按首次出现顺序排列
+type searchGroups struct {
+	roots []*searchGroup
+}
+
+// add 将条件加入 path 指定的分组，path 以 "." 分隔表示嵌套，例如 kw.detail
+func (g *searchGroups) add(path, logic, expr string, args []interface{}) {
+	nodes := &g.roots
+	var node *searchGroup
+	for _, name := range strings.Split(path, ".") {
+		node = nil
+		for _, n := range *nodes {
+			if n.name == name {
+				node = n
+				break
+			}
+		}
+		if node == nil {
+			node = &searchGroup{name: name}
+			*nodes = append(*nodes, node)
+		}
+		nodes = &node.children
+	}
+	if node.logic == "" {
+		node.logic = logic
+	}
+	node.exprs = append(node.exprs, expr)
+	node.args = append(node.args, args...)
+}
+
+// flush 将所有分组以 AND 写入 condition
+func (g *searchGroups) flush(condition Condition) {
+	for _, root := range g.roots {
+		sql, args := root.build()
+		if sql == "" {
+			continue
+		}
+		condition.SetWhere(sql, args)
+	}
+	g.roots = nil
+}
+
+// build 生成括号包裹的 SQL 片段及参数，子分组排在本组条件之后
+func (n *searchGroup) build() (string, []interface{}) {
+	parts := make([]string, 0, len(n.exprs)+len(n.children))
+	args := make([]interface{}, 0, len(n.args))
+	parts = append(parts, n.exprs...)
+	args = append(args, n.args...)
+	for _, child := range n.children {
+		sql, childArgs := child.build()
+		if sql == "" {
+			continue
+		}
+		parts = append(parts, sql)
+		args = append(args, childArgs...)
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	sep := " AND "
+	if n.logic == LogicOr {
+		sep = " OR "
+	}
+	return "(" + strings.Join(parts, sep) + ")", args
+}
@@ -0,0 +1,179 @@
+package gormx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/qiaogw/sub-sdk/gormx/gen"
+	"gorm.io/gorm"
+)
+
+// keywordColumns 缓存各表的文本列，key 为 keywordKey
+var keywordColumns sync.Map
+
+// keywordKey 文本列缓存键
+type keywordKey struct {
+	dialect  string
+	database string
+	table    string
+}
+
+// KeywordSearch 关键字模糊查询，生成参数化的 (col1 LIKE ? OR col2 LIKE ? ...) 条件
+// 仅匹配表中的文本列（char/varchar/text 等），不区分大小写；table 为空时使用当前 Model 的表名
+// model 为可选的 search 查询对象，其中标记了 keyword 的字段作为列白名单，e.g. search:"column:name;keyword"
+func KeywordSearch(table, key string, model interface{}) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		key := strings.TrimSpace(key)
+		if key == "" {
+			return db
+		}
+		table := table
+		if table == "" {
+			dest := db.Statement.Model
+			if dest == nil {
+				dest = db.Statement.Dest
+			}
+			if err := db.Statement.Parse(dest); err != nil {
+				_ = db.AddError(err)
+				return db
+			}
+			table = db.Statement.Table
+		}
+		columns, err := keywordColumnsOf(db, table, model)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		// 没有可检索的列时不返回任何数据
+		if len(columns) == 0 {
+			return db.Where("1 = 0")
+		}
+		d := DialectOf(db)
+		value := "%" + d.EscapeLike(key) + "%"
+		exprs := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, column := range columns {
+			exprs[i] = d.Like(columnRef(d, table, column), true)
+			args[i] = value
+		}
+		return db.Where("("+strings.Join(exprs, " OR ")+")", args...)
+	}
+}
+
+// InvalidateKeywordColumns 清除表的文本列缓存，表结构变更后调用；未指定表时全部清除
+func InvalidateKeywordColumns(tables ...string) {
+	keywordColumns.Range(func(key, _ any) bool {
+		if len(tables) == 0 {
+			keywordColumns.Delete(key)
+			return true
+		}
+		for _, table := range tables {
+			if key.(keywordKey).table == table {
+				keywordColumns.Delete(key)
+			}
+		}
+		return true
+	})
+}
+
+// keywordColumnsOf 返回参与关键字查询的列，设置白名单时取白名单与文本列的交集
+func keywordColumnsOf(db *gorm.DB, table string, model interface{}) ([]string, error) {
+	whitelist := keywordWhitelist(model)
+	columns, err := textColumns(db, table)
+	if err != nil {
+		// 无法读取表结构时仅使用白名单
+		if len(whitelist) > 0 {
+			return whitelist, nil
+		}
+		return nil, err
+	}
+	if len(whitelist) == 0 {
+		return columns, nil
+	}
+	text := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		text[c] = true
+	}
+	allowed := make([]string, 0, len(whitelist))
+	for _, c := range whitelist {
+		if text[c] {
+			allowed = append(allowed, c)
+		}
+	}
+	return allowed, nil
+}
+
+// textColumns 通过 gen.DbService 读取表的文本列并缓存
+func textColumns(db *gorm.DB, table string) ([]string, error) {
+	// 使用新会话，避免在 scope 中执行查询污染当前语句
+	tx := db.Session(&gorm.Session{NewDB: true, Context: db.Statement.Context})
+	database := tx.Migrator().CurrentDatabase()
+	key := keywordKey{dialect: dialectorName(db), database: database, table: table}
+	if v, ok := keywordColumns.Load(key); ok {
+		return v.([]string), nil
+	}
+	genApp, err := gen.NewAutoCodeServiceByDB(tx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := genApp.DB.GetColumn(database, table)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil || len(data.Columns) == 0 {
+		return nil, fmt.Errorf("数据表 %s 不存在或没有字段", table)
+	}
+	columns := make([]string, 0, len(data.Columns))
+	seen := make(map[string]bool, len(data.Columns))
+	for _, c := range data.Columns {
+		// 同一字段存在多个索引时会重复返回
+		if c.DbColumn == nil || seen[c.Name] || !isTextType(c.DataType) {
+			continue
+		}
+		seen[c.Name] = true
+		columns = append(columns, c.Name)
+	}
+	keywordColumns.Store(key, columns)
+	return columns, nil
+}
+
+// isTextType 判断数据库字段类型是否为文本类型
+func isTextType(dataType string) bool {
+	t := strings.ToLower(dataType)
+	return strings.Contains(t, "char") || strings.Contains(t, "text") || strings.Contains(t, "clob")
+}
+
+// keywordWhitelist 读取查询对象中标记了 keyword 的字段列名
+func keywordWhitelist(model interface{}) []string {
+	if model == nil {
+		return nil
+	}
+	typ := reflect.TypeOf(model)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	var columns []string
+	var walk func(typ reflect.Type)
+	walk = func(typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			tag, ok := sf.Tag.Lookup(FromQueryTag)
+			if !ok {
+				if sf.Type.Kind() == reflect.Struct {
+					walk(sf.Type)
+				}
+				continue
+			}
+			if t := makeTag(tag); t.Keyword && t.Column != "" {
+				columns = append(columns, t.Column)
+			}
+		}
+	}
+	walk(typ)
+	return columns
+}
@@ -0,0 +1,42 @@
+package gormx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeywordSearch(t *testing.T) {
+	db := newCursorDB(t)
+	assert.Nil(t, db.Model(&searchUser{}).Where("id = ?", 2).Update("code", "A_b%").Error)
+	assert.Nil(t, db.Model(&searchUser{}).Where("id = ?", 3).Update("name", "xa_b%").Error)
+	defer InvalidateKeywordColumns()
+
+	assert.Equal(t, []string{"name", "code"}, func() []string {
+		columns, err := textColumns(db, "user")
+		assert.Nil(t, err)
+		return columns
+	}())
+
+	var list []searchUser
+	// 通配符按字面量匹配，不区分大小写
+	assert.Nil(t, db.Scopes(KeywordSearch("", "a_B%", nil)).Order("id").Find(&list).Error)
+	assert.Equal(t, []int64{2, 3}, cursorIds(list))
+
+	// 关键字中的引号作为参数传递
+	assert.Nil(t, db.Scopes(KeywordSearch("user", "' OR 1=1 --", nil)).Find(&list).Error)
+	assert.Empty(t, list)
+
+	type query struct {
+		Name string `search:"type:contains;column:name;keyword"`
+	}
+	assert.Nil(t, db.Scopes(KeywordSearch("user", "a_b", query{})).Find(&list).Error)
+	assert.Equal(t, []int64{3}, cursorIds(list))
+
+	// 白名单中的非文本列被忽略
+	type statusQuery struct {
+		Status int64 `search:"column:status;keyword"`
+	}
+	assert.Nil(t, db.Scopes(KeywordSearch("user", "1", statusQuery{})).Find(&list).Error)
+	assert.Empty(t, list)
+}
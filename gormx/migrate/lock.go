@@ -0,0 +1,153 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// lockRetryInterval 等待迁移锁时的重试间隔
+const lockRetryInterval = 200 * time.Millisecond
+
+type (
+	// migrationLock 锁表记录，用于不支持会话锁的数据库
+	migrationLock struct {
+		Id       int `gorm:"primaryKey;autoIncrement:false"`
+		LockedAt time.Time
+	}
+
+	// sqlRecorder dry-run 时记录 SQL 的 logger
+	sqlRecorder struct {
+		w io.Writer
+	}
+)
+
+// withLock 持有迁移锁时执行 fn
+// MySQL 使用 GET_LOCK，Postgres 使用 pg_try_advisory_lock，均在同一连接上加锁并执行迁移，连接断开时自动释放；
+// 其他数据库向 <表名>_lock 表插入记录，进程异常退出后需调用 Unlock 释放
+func (m *Migrator) withLock(db *gorm.DB, fn func(db *gorm.DB) error) error {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return db.Connection(func(conn *gorm.DB) error {
+			var got sql.NullInt64
+			err := conn.Raw("SELECT GET_LOCK(?, ?)", m.lockName(), int(m.lockTimeout.Seconds())).Scan(&got).Error
+			if err != nil {
+				return err
+			}
+			if got.Int64 != 1 {
+				return ErrLocked
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", m.lockName())
+			return fn(conn)
+		})
+	case "postgres":
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(m.lockName()))
+		key := int64(h.Sum64())
+		return db.Connection(func(conn *gorm.DB) error {
+			err := m.retry(conn.Statement.Context, func() (bool, error) {
+				var got bool
+				err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&got).Error
+				return got, err
+			})
+			if err != nil {
+				return err
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", key)
+			return fn(conn)
+		})
+	default:
+		// 多个实例可能同时建表，建表失败但表已存在时忽略
+		if err := db.Table(m.lockTable()).AutoMigrate(&migrationLock{}); err != nil && !db.Migrator().HasTable(m.lockTable()) {
+			return err
+		}
+		err := m.retry(db.Statement.Context, func() (bool, error) {
+			err := db.Table(m.lockTable()).Create(&migrationLock{Id: 1, LockedAt: time.Now()}).Error
+			if err == nil {
+				return true, nil
+			}
+			// 仅主键冲突视为锁已被持有，表不存在、只读、连接断开等错误直接返回
+			if m.lockHeld(db, err) {
+				return false, nil
+			}
+			return false, err
+		})
+		if err != nil {
+			return err
+		}
+		defer m.unlock(db)
+		return fn(db)
+	}
+}
+
+// Unlock 强制释放锁表中的迁移锁，用于迁移进程异常退出后；MySQL、Postgres 的会话锁无需释放
+func (m *Migrator) Unlock(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(m.lockTable()) {
+		return nil
+	}
+	return m.unlock(db)
+}
+
+func (m *Migrator) unlock(db *gorm.DB) error {
+	return db.Table(m.lockTable()).Where("id = ?", 1).Delete(&migrationLock{}).Error
+}
+
+// lockHeld 插入锁记录的错误是否因为锁已被持有：驱动识别为主键冲突，或锁记录已存在
+func (m *Migrator) lockHeld(db *gorm.DB, err error) bool {
+	if t, ok := db.Dialector.(gorm.ErrorTranslator); ok && errors.Is(t.Translate(err), gorm.ErrDuplicatedKey) {
+		return true
+	}
+	var n int64
+	return db.Table(m.lockTable()).Where("id = ?", 1).Count(&n).Error == nil && n > 0
+}
+
+// retry 在 lockTimeout 内重试加锁
+func (m *Migrator) retry(ctx context.Context, try func() (bool, error)) error {
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		ok, err := try()
+		if err != nil || ok {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return ErrLocked
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+func (m *Migrator) lockName() string {
+	return "gormx-migrate:" + m.table
+}
+
+func (m *Migrator) lockTable() string {
+	return m.table + "_lock"
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface {
+	return r
+}
+
+func (r *sqlRecorder) Info(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Warn(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Error(context.Context, string, ...interface{}) {}
+
+// Trace 输出 dry-run 生成的 SQL
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	stmt, _ := fc()
+	_, _ = fmt.Fprintln(r.w, stmt+";")
+}
@@ -0,0 +1,296 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+// DefaultTable 默认的迁移记录表
+const DefaultTable = "schema_migrations"
+
+var (
+	// ErrLocked 等待迁移锁超时
+	ErrLocked = errors.New("migrate: 等待迁移锁超时，可能有其他实例正在迁移")
+	// ErrUnknownVersion 目标版本或已执行的版本未注册
+	ErrUnknownVersion = errors.New("migrate: 版本未注册")
+)
+
+type (
+	// Migration 一个版本的迁移，Up、Down 在事务中执行，NoTx 为 true 时不使用事务
+	Migration struct {
+		Version int64  // 版本号，按升序执行，e.g. 20240101120000
+		Name    string // 名称，仅用于展示
+		Up      func(tx *gorm.DB) error
+		Down    func(tx *gorm.DB) error // 为空时不支持回滚
+		NoTx    bool                    // 不在事务中执行，用于 CREATE INDEX CONCURRENTLY 等语句
+	}
+
+	// Status 迁移状态
+	Status struct {
+		Version   int64
+		Name      string
+		Applied   bool
+		AppliedAt time.Time
+		Missing   bool // 已执行但未注册
+	}
+
+	// Option 定义 Migrator 的可选参数
+	Option func(m *Migrator)
+
+	// Migrator 版本化迁移，执行记录保存在 schema_migrations 表，多实例同时执行时通过迁移锁串行
+	Migrator struct {
+		db          *gorm.DB
+		migrations  []*Migration
+		table       string
+		lockTimeout time.Duration
+		dryRun      io.Writer
+	}
+
+	// schemaMigration 迁移记录
+	schemaMigration struct {
+		Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+		Name      string `gorm:"size:255"`
+		AppliedAt time.Time
+	}
+)
+
+// WithTable 设置迁移记录表，默认 schema_migrations，迁移锁表为 <表名>_lock
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLockTimeout 设置等待迁移锁的时间，默认 1 分钟
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// WithDryRun 不执行迁移，将待执行的 SQL 输出到 w
+func WithDryRun(w io.Writer) Option {
+	return func(m *Migrator) {
+		m.dryRun = w
+	}
+}
+
+// New 创建迁移器，db 可为 configx.Open 返回的任意连接
+func New(db *gorm.DB, opts ...Option) *Migrator {
+	m := &Migrator{db: db, table: DefaultTable, lockTimeout: time.Minute}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Add 注册迁移，版本号不能重复
+func (m *Migrator) Add(migrations ...Migration) error {
+	for i := range migrations {
+		mg := migrations[i]
+		if mg.Up == nil {
+			return fmt.Errorf("migrate: 版本 %d 未定义 Up", mg.Version)
+		}
+		if m.find(mg.Version) != nil {
+			return fmt.Errorf("migrate: 版本 %d 重复注册", mg.Version)
+		}
+		m.migrations = append(m.migrations, &mg)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool { return m.migrations[i].Version < m.migrations[j].Version })
+	return nil
+}
+
+// Up 执行全部未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(applied map[int64]schemaMigration) ([]*Migration, []*Migration, error) {
+		return m.pending(applied, math.MaxInt64), nil, nil
+	})
+}
+
+// Down 按版本倒序回滚最近执行的 n 个迁移，n 为 0 时不回滚，小于 0 时返回错误；全部回滚请使用 To(ctx, 0)
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n < 0 {
+		return fmt.Errorf("migrate: 回滚数量无效: %d", n)
+	}
+	if n == 0 {
+		return nil
+	}
+	return m.run(ctx, func(applied map[int64]schemaMigration) ([]*Migration, []*Migration, error) {
+		down, err := m.appliedDesc(applied, n, func(int64) bool { return true })
+		return nil, down, err
+	})
+}
+
+// To 迁移到 version：执行不大于 version 的未执行迁移，回滚大于 version 的已执行迁移；version 为 0 时全部回滚
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return m.run(ctx, func(applied map[int64]schemaMigration) ([]*Migration, []*Migration, error) {
+		down, err := m.appliedDesc(applied, -1, func(v int64) bool { return v > version })
+		return m.pending(applied, version), down, err
+	})
+}
+
+// Status 返回已注册及已执行的全部迁移的状态，按版本升序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Status, 0, len(m.migrations)+len(applied))
+	for _, mg := range m.migrations {
+		rec, ok := applied[mg.Version]
+		list = append(list, Status{Version: mg.Version, Name: mg.Name, Applied: ok, AppliedAt: rec.AppliedAt})
+	}
+	for v, rec := range applied {
+		if m.find(v) == nil {
+			list = append(list, Status{Version: v, Name: rec.Name, Applied: true, AppliedAt: rec.AppliedAt, Missing: true})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// run 持有迁移锁时创建记录表、读取执行记录，按 plan 先回滚再执行；dry-run 时不加锁，只输出 SQL
+func (m *Migrator) run(ctx context.Context,
+	plan func(applied map[int64]schemaMigration) (up, down []*Migration, err error)) error {
+	exec := func(db *gorm.DB) error {
+		if m.dryRun == nil {
+			if err := db.Table(m.table).AutoMigrate(&schemaMigration{}); err != nil {
+				return err
+			}
+		}
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		up, down, err := plan(applied)
+		if err != nil {
+			return err
+		}
+		for _, mg := range down {
+			if err = m.apply(db, mg, false); err != nil {
+				return err
+			}
+		}
+		for _, mg := range up {
+			if err = m.apply(db, mg, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	db := m.db.WithContext(ctx)
+	if m.dryRun != nil {
+		return exec(db)
+	}
+	return m.withLock(db, exec)
+}
+
+// apply 执行一个迁移并更新执行记录
+func (m *Migrator) apply(db *gorm.DB, mg *Migration, up bool) error {
+	fn, direction := mg.Up, "up"
+	if !up {
+		fn, direction = mg.Down, "down"
+	}
+	if fn == nil {
+		return fmt.Errorf("migrate: 版本 %d 不支持回滚", mg.Version)
+	}
+	if m.dryRun != nil {
+		if _, err := fmt.Fprintf(m.dryRun, "-- %d %s (%s)\n", mg.Version, mg.Name, direction); err != nil {
+			return err
+		}
+		return fn(db.Session(&gorm.Session{DryRun: true, Logger: &sqlRecorder{w: m.dryRun}}))
+	}
+	record := func(tx *gorm.DB) error {
+		if up {
+			return tx.Table(m.table).Create(&schemaMigration{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now()}).Error
+		}
+		return tx.Table(m.table).Where("version = ?", mg.Version).Delete(&schemaMigration{}).Error
+	}
+	var err error
+	if mg.NoTx {
+		if err = fn(db); err == nil {
+			err = record(db)
+		}
+	} else {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := fn(tx); err != nil {
+				return err
+			}
+			return record(tx)
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("migrate: 版本 %d %s %s 出错: %w", mg.Version, mg.Name, direction, err)
+	}
+	logx.WithContext(db.Statement.Context).Infof("migrate: 版本 %d %s %s 完成", mg.Version, mg.Name, direction)
+	return nil
+}
+
+// applied 读取执行记录，记录表不存在时视为未执行任何迁移
+func (m *Migrator) applied(db *gorm.DB) (map[int64]schemaMigration, error) {
+	applied := map[int64]schemaMigration{}
+	if !db.Migrator().HasTable(m.table) {
+		return applied, nil
+	}
+	var list []schemaMigration
+	if err := db.Table(m.table).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, rec := range list {
+		applied[rec.Version] = rec
+	}
+	return applied, nil
+}
+
+// pending 不大于 max 的未执行迁移
+func (m *Migrator) pending(applied map[int64]schemaMigration, max int64) []*Migration {
+	var list []*Migration
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; !ok && mg.Version <= max {
+			list = append(list, mg)
+		}
+	}
+	return list
+}
+
+// appliedDesc 按版本倒序返回满足 match 的前 limit 个已执行迁移，limit 小于 0 时不限制；
+// 已执行版本未注册时返回 ErrUnknownVersion
+func (m *Migrator) appliedDesc(applied map[int64]schemaMigration, limit int, match func(v int64) bool) ([]*Migration, error) {
+	versions := make([]int64, 0, len(applied))
+	for v := range applied {
+		if match(v) {
+			versions = append(versions, v)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	if limit >= 0 && limit < len(versions) {
+		versions = versions[:limit]
+	}
+	list := make([]*Migration, len(versions))
+	for i, v := range versions {
+		if list[i] = m.find(v); list[i] == nil {
+			return nil, fmt.Errorf("%w: 已执行的版本 %d 无法回滚", ErrUnknownVersion, v)
+		}
+	}
+	return list, nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return mg
+		}
+	}
+	return nil
+}
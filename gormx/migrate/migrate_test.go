@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var testFS = fstest.MapFS{
+	"sql/20240101000000_create_user.sql": {Data: []byte(`
+-- +migrate Up
+CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT);
+CREATE INDEX idx_user_name
+    ON user (name);
+-- +migrate StatementBegin
+CREATE TRIGGER user_name AFTER INSERT ON user BEGIN
+    UPDATE user SET name = upper(name) WHERE id = new.id;
+END;
+-- +migrate StatementEnd
+
+-- +migrate Down
+DROP TABLE user;
+`)},
+	"sql/20240102000000_create_role.sql": {Data: []byte(`
+-- +migrate Up
+CREATE TABLE role (id INTEGER PRIMARY KEY);
+-- +migrate Down
+DROP TABLE role;
+`)},
+}
+
+func newTestMigrator(t *testing.T, db *gorm.DB, opts ...Option) *Migrator {
+	m := New(db, opts...)
+	assert.Nil(t, m.AddFS(testFS, "sql"))
+	assert.Nil(t, m.Add(Migration{
+		Version: 20240103000000,
+		Name:    "seed_user",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("INSERT INTO user (id, name) VALUES (?, ?)", 1, "admin").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DELETE FROM user WHERE id = ?", 1).Error
+		},
+	}))
+	return m
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "migrate.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	m := newTestMigrator(t, db)
+	assert.NotNil(t, m.Add(Migration{Version: 20240101000000, Up: func(*gorm.DB) error { return nil }}))
+
+	// dry-run 只输出 SQL
+	var out bytes.Buffer
+	assert.Nil(t, newTestMigrator(t, db, WithDryRun(&out)).Up(ctx))
+	assert.Contains(t, out.String(), "-- 20240101000000 create_user (up)\nCREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT);")
+	assert.Contains(t, out.String(), "INSERT INTO user (id, name) VALUES (1, \"admin\");")
+	assert.False(t, db.Migrator().HasTable("user"))
+	assert.False(t, db.Migrator().HasTable(DefaultTable))
+
+	// 多个实例同时执行时只执行一次
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+			assert.Nil(t, err)
+			assert.Nil(t, newTestMigrator(t, db, WithLockTimeout(10*time.Second)).Up(ctx))
+		}()
+	}
+	wg.Wait()
+	var name string
+	assert.Nil(t, db.Raw("SELECT name FROM user WHERE id = 1").Scan(&name).Error)
+	assert.Equal(t, "ADMIN", name)
+	status, err := m.Status(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, status, 3)
+	for _, s := range status {
+		assert.True(t, s.Applied)
+	}
+
+	// 回滚与迁移到指定版本，回滚数量为负数时拒绝，为 0 时不回滚
+	assert.NotNil(t, m.Down(ctx, -1))
+	assert.Nil(t, m.Down(ctx, 0))
+	assert.True(t, db.Migrator().HasTable("role"))
+	assert.Nil(t, m.Down(ctx, 2))
+	assert.True(t, db.Migrator().HasTable("user"))
+	assert.False(t, db.Migrator().HasTable("role"))
+	assert.Nil(t, m.To(ctx, 20240102000000))
+	assert.True(t, db.Migrator().HasTable("role"))
+	var n int64
+	assert.Nil(t, db.Raw("SELECT count(*) FROM user").Scan(&n).Error)
+	assert.Equal(t, int64(0), n)
+	assert.Nil(t, m.To(ctx, 0))
+	assert.False(t, db.Migrator().HasTable("user"))
+	assert.ErrorIs(t, m.To(ctx, 1), ErrUnknownVersion)
+
+	// 已执行但未注册的版本
+	assert.Nil(t, m.Up(ctx))
+	status, err = New(db).Status(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, status, 3)
+	assert.True(t, status[0].Missing)
+	assert.ErrorIs(t, New(db).Down(ctx, 1), ErrUnknownVersion)
+
+	// 锁被持有时等待超时
+	assert.Nil(t, db.Table(DefaultTable+"_lock").Create(&migrationLock{Id: 1}).Error)
+	assert.ErrorIs(t, New(db, WithLockTimeout(time.Millisecond)).Up(ctx), ErrLocked)
+	assert.Nil(t, m.Unlock(ctx))
+	assert.Nil(t, m.Up(ctx))
+
+	// 锁表写入出错时直接返回，不等待超时
+	lockErr := errors.New("read-only")
+	assert.Nil(t, db.Callback().Create().Before("gorm:create").Register("test:lock", func(tx *gorm.DB) {
+		if tx.Statement.Table == DefaultTable+"_lock" {
+			_ = tx.AddError(lockErr)
+		}
+	}))
+	assert.ErrorIs(t, New(db, WithLockTimeout(time.Minute)).Up(ctx), lockErr)
+}
+
+func TestParseSQL(t *testing.T) {
+	mg, err := parseSQL("-- +migrate Up notransaction\nCREATE INDEX idx ON t (a);\n")
+	assert.Nil(t, err)
+	assert.True(t, mg.NoTx)
+	assert.Nil(t, mg.Down)
+	_, err = parseSQL("CREATE TABLE t (a INT);")
+	assert.NotNil(t, err)
+	_, _, err = parseFileName("create_user.sql")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SQL 迁移文件中的指令
+const (
+	directivePrefix = "-- +migrate"
+	directiveUp     = "Up"
+	directiveDown   = "Down"
+	// 多条语句组成的一条语句的开始与结束，如存储过程、触发器
+	directiveBegin = "StatementBegin"
+	directiveEnd   = "StatementEnd"
+	// 跟在 Up 后面，表示不在事务中执行
+	optionNoTx = "notransaction"
+)
+
+// AddFS 注册 fsys 中 dir 目录下的 .sql 迁移文件，通常配合 embed.FS 使用
+// 文件名为 <版本号>_<名称>.sql，内容以 "-- +migrate Up"、"-- +migrate Down" 分为执行与回滚两部分，
+// 语句以行尾的分号分隔，包含分号的单条语句放在 "-- +migrate StatementBegin"、"-- +migrate StatementEnd" 之间；
+// "-- +migrate Up notransaction" 表示不在事务中执行
+func (m *Migrator) AddFS(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		version, name, err := parseFileName(path.Base(file))
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		mg, err := parseSQL(string(data))
+		if err != nil {
+			return fmt.Errorf("migrate: 解析 %s 出错: %w", file, err)
+		}
+		mg.Version, mg.Name = version, name
+		if err = m.Add(mg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFileName 解析 <版本号>_<名称>.sql
+func parseFileName(file string) (int64, string, error) {
+	base := strings.TrimSuffix(file, ".sql")
+	num, name, _ := strings.Cut(base, "_")
+	version, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", fmt.Errorf("migrate: 迁移文件名 %s 应为 <版本号>_<名称>.sql", file)
+	}
+	return version, name, nil
+}
+
+// parseSQL 将 SQL 文件拆分为执行与回滚语句
+func parseSQL(content string) (Migration, error) {
+	var (
+		mg             Migration
+		up, down       []string
+		current        *[]string
+		buf            strings.Builder
+		inStatement    bool
+		hasUp, hasDown bool
+	)
+	flush := func() {
+		if stmt := strings.TrimSpace(buf.String()); stmt != "" && current != nil {
+			*current = append(*current, stmt)
+		}
+		buf.Reset()
+	}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, directivePrefix) {
+			fields := strings.Fields(strings.TrimPrefix(trimmed, directivePrefix))
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case directiveUp:
+				flush()
+				current, hasUp = &up, true
+				for _, opt := range fields[1:] {
+					if strings.EqualFold(opt, optionNoTx) {
+						mg.NoTx = true
+					}
+				}
+			case directiveDown:
+				flush()
+				current, hasDown = &down, true
+			case directiveBegin:
+				flush()
+				inStatement = true
+			case directiveEnd:
+				inStatement = false
+				flush()
+			}
+			continue
+		}
+		if current == nil || buf.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		if !inStatement && strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return mg, err
+	}
+	if inStatement {
+		return mg, fmt.Errorf("缺少 %s %s", directivePrefix, directiveEnd)
+	}
+	flush()
+	if !hasUp {
+		return mg, fmt.Errorf("缺少 %s %s", directivePrefix, directiveUp)
+	}
+	mg.Up = execStatements(up)
+	if hasDown {
+		mg.Down = execStatements(down)
+	}
+	return mg, nil
+}
+
+// execStatements 依次执行 SQL 语句
+func execStatements(stmts []string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -34,6 +34,27 @@ type ModelUtils struct {
 	Enabled bool   `json:"enabled" comment:"可用" gorm:"column:enabled;comment:可用状态;"`
 }
 
+// Sortable 可排序的模型，嵌入 ModelUtils 即实现；启用 gormx.SortPlugin 时新增记录的 sort 为零值则排在分组末尾
+type Sortable interface {
+	GetSort() int64
+	SetSort(sort int64)
+}
+
+// SortGrouper 排序分组，返回分组列，e.g. parent_id；未实现时整表为一组
+type SortGrouper interface {
+	SortGroupColumn() string
+}
+
+// GetSort 获取排序值
+func (m *ModelUtils) GetSort() int64 {
+	return m.Sort
+}
+
+// SetSort 设置排序值
+func (m *ModelUtils) SetSort(sort int64) {
+	m.Sort = sort
+}
+
 // ModelWithCommon 通用模型
 type ModelWithCommon struct {
 	BaseModel
@@ -49,6 +70,7 @@ type Pagination struct {
 	SortBY     string `json:"sortBy,optional" gorm:"-"`
 	Descending bool   `json:"descending,optional" gorm:"-"`
 	SearchKey  string `json:"searchKey"  gorm:"-"`
+	Cursor     string `form:"cursor,optional" json:"cursor,optional" gorm:"-"` // 游标分页令牌，为空时从第一页开始
 }
 
 func (m *Pagination) GetPageIndex() int64 {
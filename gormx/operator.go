@@ -0,0 +1,198 @@
+package gormx
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Range 区间查询值，用于 between，From/To 为零值时表示该端不限
+// e.g. CreatedAt gormx.Range[time.Time] `search:"type:between;column:created_at"`
+type Range[T any] struct {
+	From T `json:"from" form:"from"`
+	To   T `json:"to" form:"to"`
+}
+
+// jsonPathSegment JSON 路径段只允许字母、数字和下划线，避免拼接进 SQL 时被注入
+var jsonPathSegment = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// betweenExpr 生成区间条件，value 为两个元素的切片/数组，或包含 From/To 字段的结构体
+func betweenExpr(columnRef string, value reflect.Value) (string, []interface{}, bool) {
+	var from, to reflect.Value
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if value.Len() != 2 {
+			return "", nil, false
+		}
+		from, to = value.Index(0), value.Index(1)
+	case reflect.Struct:
+		from, to = value.FieldByName("From"), value.FieldByName("To")
+		if !from.IsValid() || !to.IsValid() {
+			return "", nil, false
+		}
+	default:
+		return "", nil, false
+	}
+	switch {
+	case !from.IsZero() && !to.IsZero():
+		return fmt.Sprintf("%s BETWEEN ? AND ?", columnRef), []interface{}{from.Interface(), to.Interface()}, true
+	case !from.IsZero():
+		return fmt.Sprintf("%s >= ?", columnRef), []interface{}{from.Interface()}, true
+	case !to.IsZero():
+		return fmt.Sprintf("%s <= ?", columnRef), []interface{}{to.Interface()}, true
+	}
+	return "", nil, false
+}
+
+// jsonPath 将 a.b.0 形式的路径转换为各数据库的 JSON 路径写法
+// MySQL/SQLite: '$.a.b[0]'，Postgres: '{a,b,0}'
+func jsonPath(driver, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	segments := strings.Split(path, ".")
+	for _, s := range segments {
+		if !jsonPathSegment.MatchString(s) {
+			return "", false
+		}
+	}
+	if driver == Postgres {
+		return "'{" + strings.Join(segments, ",") + "}'", true
+	}
+	var b strings.Builder
+	b.WriteString("'$")
+	for _, s := range segments {
+		if isDigits(s) {
+			b.WriteString("[" + s + "]")
+		} else {
+			b.WriteString("." + s)
+		}
+	}
+	b.WriteString("'")
+	return b.String(), true
+}
+
+// jsonEqualExpr 生成 JSON 路径取值等于条件
+func jsonEqualExpr(driver, columnRef, path string, value reflect.Value) (string, []interface{}, bool) {
+	p, ok := jsonPath(driver, path)
+	if !ok {
+		return "", nil, false
+	}
+	switch driver {
+	case Postgres:
+		// #>> 返回 text，参数统一转为字符串比较
+		return fmt.Sprintf("%s #>> %s = ?", columnRef, p), []interface{}{fmt.Sprint(value.Interface())}, true
+	case Mysql:
+		return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, %s)) = ?", columnRef, p), []interface{}{value.Interface()}, true
+	default:
+		return fmt.Sprintf("json_extract(%s, %s) = ?", columnRef, p), []interface{}{value.Interface()}, true
+	}
+}
+
+// jsonContainsExpr 生成 JSON 包含条件，path 为空时作用于整个字段
+func jsonContainsExpr(driver, columnRef, path string, value reflect.Value) (string, []interface{}, bool) {
+	doc, err := jsonDocument(value)
+	if err != nil {
+		return "", nil, false
+	}
+	target := columnRef
+	p := ""
+	if path != "" {
+		var ok bool
+		if p, ok = jsonPath(driver, path); !ok {
+			return "", nil, false
+		}
+	}
+	switch driver {
+	case Postgres:
+		if p != "" {
+			target = fmt.Sprintf("(%s #> %s)", columnRef, p)
+		}
+		return fmt.Sprintf("%s::jsonb @> CAST(? AS jsonb)", target), []interface{}{doc}, true
+	case Mysql:
+		if p != "" {
+			return fmt.Sprintf("JSON_CONTAINS(%s, ?, %s)", columnRef, p), []interface{}{doc}, true
+		}
+		return fmt.Sprintf("JSON_CONTAINS(%s, ?)", columnRef), []interface{}{doc}, true
+	default:
+		// SQLite 无原生包含运算，按数组元素匹配
+		if p != "" {
+			target = fmt.Sprintf("%s, %s", columnRef, p)
+		}
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value = ?)", target),
+			[]interface{}{value.Interface()}, true
+	}
+}
+
+// arrayExpr 生成数组包含（@>）或相交（&&）条件
+// Postgres 使用原生数组运算，其它数据库按 JSON 数组处理
+func arrayExpr(driver, searchType, columnRef string, value reflect.Value) (string, []interface{}, bool) {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array || value.Len() == 0 {
+		return "", nil, false
+	}
+	items := make([]interface{}, value.Len())
+	for i := range items {
+		items[i] = value.Index(i).Interface()
+	}
+	switch driver {
+	case Postgres:
+		op := "@>"
+		if searchType == ArrayOverlap {
+			op = "&&"
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(items)), ",")
+		return fmt.Sprintf("%s %s ARRAY[%s]", columnRef, op, placeholders), items, true
+	case Mysql:
+		doc, err := json.Marshal(items)
+		if err != nil {
+			return "", nil, false
+		}
+		if searchType == ArrayOverlap {
+			return fmt.Sprintf("JSON_OVERLAPS(%s, ?)", columnRef), []interface{}{string(doc)}, true
+		}
+		return fmt.Sprintf("JSON_CONTAINS(%s, ?)", columnRef), []interface{}{string(doc)}, true
+	default:
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(items)), ",")
+		if searchType == ArrayOverlap {
+			return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value IN (%s))",
+				columnRef, placeholders), items, true
+		}
+		return fmt.Sprintf("(SELECT COUNT(DISTINCT json_each.value) FROM json_each(%s) WHERE json_each.value IN (%s)) = %d",
+			columnRef, placeholders, distinctCount(items)), items, true
+	}
+}
+
+// jsonDocument 将查询值编码为 JSON 文本，已是 JSON 的字节/RawMessage 原样使用
+func jsonDocument(value reflect.Value) (string, error) {
+	switch v := value.Interface().(type) {
+	case json.RawMessage:
+		return string(v), nil
+	case []byte:
+		return string(v), nil
+	}
+	if m, ok := value.Interface().(json.Marshaler); ok {
+		b, err := m.MarshalJSON()
+		return string(b), err
+	}
+	b, err := json.Marshal(value.Interface())
+	return string(b), err
+}
+
+func distinctCount(items []interface{}) int {
+	set := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		set[fmt.Sprint(item)] = struct{}{}
+	}
+	return len(set)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
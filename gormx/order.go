@@ -0,0 +1,152 @@
+package gormx
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"gorm.io/gorm"
+)
+
+// sortItem 解析后的排序项
+type sortItem struct {
+	column string         // 已引用的列
+	desc   bool           // 是否降序
+	nulls  string         // 空值位置 NullsFirst/NullsLast
+	joins  []*searchField // 排序列所在的关联，从外到内
+}
+
+// OrderBy 按 search 查询对象的字段白名单生成多列排序
+// sortBy 为逗号分隔的 json 字段名，"-" 前缀表示降序，":nullsfirst"/":nullslast" 后缀指定空值位置，关联字段以 "." 分隔
+// e.g. sortBy=createdAt,-sort,dept.name:nullslast；descending 为 true 时未加前缀的字段也按降序
+// 未声明的字段通过 db.AddError 返回错误；排序列所在的关联自动 JOIN，末尾追加主键保证顺序稳定
+func OrderBy(q interface{}, sortBy string, descending bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if strings.TrimSpace(sortBy) == "" {
+			return db
+		}
+		name, d := resolveDialect(dialectorName(db))
+		items, err := resolveSortBy(name, d, q, sortBy, descending)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		if len(items) == 0 {
+			return db
+		}
+		seen := make(map[string]bool, len(items))
+		for _, item := range items {
+			for _, j := range item.joins {
+				db = joinOnce(db, j.join)
+			}
+			db = db.Order(d.OrderBy(item.column, item.desc, item.nulls))
+			seen[item.column] = true
+		}
+		if pk := primaryKeyRef(db, d); pk != "" && !seen[pk] {
+			db = db.Order(d.OrderBy(pk, items[len(items)-1].desc, ""))
+		}
+		return db
+	}
+}
+
+// resolveSortBy 解析排序表达式，字段须在 search 标签中声明了 column
+func resolveSortBy(name string, d SearchDialect, q interface{}, sortBy string, descending bool) ([]sortItem, error) {
+	typ := reflect.TypeOf(q)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, errx.NewErrorf(errx.RequestParamError, "不支持的排序字段: %s", sortBy)
+	}
+	plan, err := loadSearchPlan(name, d, typ)
+	if err != nil {
+		return nil, err
+	}
+	var items []sortItem
+	for _, spec := range strings.Split(sortBy, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		item := sortItem{desc: descending}
+		switch spec[0] {
+		case '-':
+			item.desc = true
+			spec = spec[1:]
+		case '+':
+			item.desc = false
+			spec = spec[1:]
+		}
+		if field, nulls, ok := strings.Cut(spec, ":"); ok {
+			item.nulls = strings.ToLower(nulls)
+			if item.nulls != NullsFirst && item.nulls != NullsLast {
+				return nil, errx.NewErrorf(errx.RequestParamError, "不支持的空值排序方式: %s", nulls)
+			}
+			spec = field
+		}
+		field, joins := plan.lookup(strings.Split(spec, "."))
+		if field == nil || field.column == "" {
+			return nil, errx.NewErrorf(errx.RequestParamError, "不支持的排序字段: %s", spec)
+		}
+		item.column = field.column
+		item.joins = joins
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// primaryKeyRef 当前查询模型的主键列引用，无法解析时返回空
+func primaryKeyRef(db *gorm.DB, d SearchDialect) string {
+	model := db.Statement.Model
+	if model == nil {
+		model = db.Statement.Dest
+	}
+	if model == nil || db.Statement.Parse(model) != nil {
+		return ""
+	}
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return ""
+	}
+	return columnRef(d, db.Statement.Table, pk.DBName)
+}
+
+// joinOnce 添加 JOIN，语句已存在时跳过
+func joinOnce(db *gorm.DB, join string) *gorm.DB {
+	for _, j := range db.Statement.Joins {
+		if j.Name == join {
+			return db
+		}
+	}
+	return db.Joins(join)
+}
+
+// GetSortBy 根据传入的结构体和字段名获取对应的排序字段
+// 该函数会从结构体的字段标签中解析出对应的排序字段，并返回该字段的名称；多列排序请使用 OrderBy
+//
+// Deprecated: 标签错误或字段未声明时返回空字符串，请使用 SortColumn
+func GetSortBy(sort interface{}, fieldName string) string {
+	column, _ := SortColumn(sort, fieldName)
+	return column
+}
+
+// SortColumn 根据 search 标签获取 json 字段对应的排序列，标签错误或字段未声明 column 时返回错误
+func SortColumn(sort interface{}, fieldName string) (string, error) {
+	typ := reflect.TypeOf(sort)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return "", errx.NewErrorf(errx.RequestParamError, "不支持的排序字段: %s", fieldName)
+	}
+	name, d := resolveDialect()
+	plan, err := loadSearchPlan(name, d, typ)
+	if err != nil {
+		return "", err
+	}
+	field, _ := plan.lookup(strings.Split(fieldName, "."))
+	if field == nil || field.tag.Column == "" {
+		return "", errx.NewErrorf(errx.RequestParamError, "不支持的排序字段: %s", fieldName)
+	}
+	return field.tag.Column, nil
+}
@@ -0,0 +1,129 @@
+package gormx
+
+import (
+	"github.com/qiaogw/sub-sdk/gormx/modelx"
+	"gorm.io/gorm"
+)
+
+type (
+	// PageResult 分页查询结果
+	PageResult[T any] struct {
+		List      []T   `json:"list"`
+		Total     int64 `json:"total"`
+		PageIndex int64 `json:"pageIndex"`
+		PageSize  int64 `json:"pageSize"`
+	}
+
+	// PageOption 分页查询选项
+	PageOption func(o *pageOptions)
+
+	pageOptions struct {
+		scopes         []func(db *gorm.DB) *gorm.DB
+		skipShortCount bool
+	}
+)
+
+// WithPageScopes 追加查询条件，如数据权限 PermissionData
+func WithPageScopes(scopes ...func(db *gorm.DB) *gorm.DB) PageOption {
+	return func(o *pageOptions) {
+		o.scopes = append(o.scopes, scopes...)
+	}
+}
+
+// WithSkipShortCount 当前页不满一页时由偏移量推算总数，不再执行 COUNT
+func WithSkipShortCount() PageOption {
+	return func(o *pageOptions) {
+		o.skipShortCount = true
+	}
+}
+
+// PageQuery 按 search 查询对象和分页参数查询 T 的列表及总数，p.SearchKey 按 KeywordSearch 匹配
+// 存在 JOIN 时列表按主键子查询去重、计数按主键 COUNT(DISTINCT)，避免关联导致的重复行；计数查询去掉 ORDER BY
+// 按关联表字段排序时，一对多关联的主表记录按其第一条关联记录的位置排序（需数据库支持窗口函数，MySQL 8.0+）
+func PageQuery[T any](db *gorm.DB, p *modelx.Pagination, q interface{}, opts ...PageOption) (*PageResult[T], error) {
+	o := pageOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	result := &PageResult[T]{PageIndex: p.GetPageIndex(), PageSize: p.GetPageSize()}
+
+	tx := db.Model(new(T))
+	if q != nil {
+		tx = MakeCondition(q, "")(tx)
+	}
+	if p.SearchKey != "" {
+		tx = KeywordSearch("", p.SearchKey, q)(tx)
+	}
+	// 立即应用条件及排序（含排序所需的 JOIN），以便去重前判断是否存在 JOIN
+	for _, scope := range o.scopes {
+		tx = scope(tx)
+	}
+	tx = OrderBy(q, p.SortBY, p.Descending)(tx)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	base := tx.Session(&gorm.Session{})
+
+	list := distinctPage(base)
+	if err := Paginate(result.PageSize, result.PageIndex)(list).Find(&result.List).Error; err != nil {
+		return nil, err
+	}
+
+	offset := (result.PageIndex - 1) * result.PageSize
+	n := int64(len(result.List))
+	// 不满一页且非越界页时总数可直接推算
+	if o.skipShortCount && n < result.PageSize && (n > 0 || offset == 0) {
+		result.Total = offset + n
+		return result, nil
+	}
+	total, err := countPage(base)
+	if err != nil {
+		return nil, err
+	}
+	result.Total = total
+	return result, nil
+}
+
+// distinctPage 存在 JOIN 时改为按主键筛选主表，关联产生的重复行不进入列表；
+// 有排序时在关联查询内按 ORDER BY 计算行号，主表按每个主键的最小行号排序，排序可引用关联表的列
+func distinctPage(base *gorm.DB) *gorm.DB {
+	if len(base.Statement.Joins) == 0 || len(base.Statement.Selects) > 0 {
+		return base
+	}
+	_, d := resolveDialect(dialectorName(base))
+	pk := primaryKeyRef(base, d)
+	if pk == "" {
+		return base
+	}
+	tx := base.Session(&gorm.Session{NewDB: true}).Model(base.Statement.Model)
+	if base.Statement.Unscoped {
+		tx = tx.Unscoped()
+	}
+	order, ok := base.Statement.Clauses["ORDER BY"]
+	if !ok {
+		return tx.Where(pk+" IN (?)", base.Select(pk)).Session(&gorm.Session{})
+	}
+
+	rows := base.Select(pk+" AS page_pk, ROW_NUMBER() OVER (?) AS page_rank", order.Expression)
+	delete(rows.Statement.Clauses, "ORDER BY")
+	keys := base.Session(&gorm.Session{NewDB: true}).Table("(?) AS page_rows", rows).
+		Select("page_pk, MIN(page_rank) AS page_rank").Group("page_pk")
+	return tx.Joins("JOIN (?) AS page_keys ON page_keys.page_pk = "+pk, keys).
+		Order("page_keys.page_rank").Session(&gorm.Session{})
+}
+
+// countPage 计数查询，去掉排序与分页
+func countPage(base *gorm.DB) (int64, error) {
+	tx := base.Offset(-1).Limit(-1)
+	delete(tx.Statement.Clauses, "ORDER BY")
+	if len(tx.Statement.Joins) > 0 && len(tx.Statement.Selects) == 0 {
+		if err := tx.Statement.Parse(tx.Statement.Model); err == nil {
+			if pk := tx.Statement.Schema.PrioritizedPrimaryField; pk != nil {
+				tx = tx.Distinct(tx.Statement.Table + "." + pk.DBName)
+			}
+		}
+	}
+	var total int64
+	err := tx.Count(&total).Error
+	return total, err
+}
@@ -0,0 +1,83 @@
+package gormx
+
+import (
+	"testing"
+
+	"github.com/qiaogw/sub-sdk/gormx/modelx"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type pageTag struct {
+	Id     int64
+	UserId int64
+	Name   string
+}
+
+func (pageTag) TableName() string {
+	return "tag"
+}
+
+func TestPageQuery(t *testing.T) {
+	db := newCursorDB(t)
+	assert.Nil(t, db.AutoMigrate(&pageTag{}))
+	assert.Nil(t, db.Create(&[]pageTag{{UserId: 1, Name: "a"}, {UserId: 1, Name: "a"}, {UserId: 2, Name: "a"}}).Error)
+
+	type tagQuery struct {
+		Name string `json:"name" search:"type:exact;table:tag;column:name"`
+	}
+	type query struct {
+		Status int64    `json:"status" search:"type:exact;table:user;column:status"`
+		Tag    tagQuery `json:"tag" search:"type:inner;table:user;join:tag;on:user_id:id"`
+		Id     string   `json:"id" search:"type:order;table:user;column:id"`
+	}
+
+	page, err := PageQuery[searchUser](db, &modelx.Pagination{PageSize: 2, SortBY: "id", Descending: true}, query{Status: 1, Id: "asc"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, []int64{1, 4}, cursorIds(page.List))
+
+	// JOIN 产生重复行时按主键去重，数据权限等条件通过 WithPageScopes 传入
+	scope := WithPageScopes(func(db *gorm.DB) *gorm.DB {
+		return db.Where("`user`.`id` < ?", 7)
+	})
+	page, err = PageQuery[searchUser](db, &modelx.Pagination{PageSize: 10, SortBY: "id"}, query{Tag: tagQuery{Name: "a"}}, scope)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Equal(t, []int64{1, 2}, cursorIds(page.List))
+
+	page, err = PageQuery[searchUser](db, &modelx.Pagination{PageSize: 10}, query{Tag: tagQuery{Name: "a"}}, scope, WithSkipShortCount())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Len(t, page.List, 2)
+
+	// 按关联表字段排序，一对多关联不产生重复行，总数与列表一致
+	assert.Nil(t, db.Create(&[]pageTag{{UserId: 3, Name: "0"}, {UserId: 1, Name: "z"}}).Error)
+	type sortTag struct {
+		Name string `json:"name" search:"type:order;table:tag;column:name"`
+	}
+	type sortQuery struct {
+		Tag sortTag `json:"tag" search:"type:inner;table:user;join:tag;on:user_id:id"`
+	}
+	page, err = PageQuery[searchUser](db, &modelx.Pagination{PageSize: 10, SortBY: "tag.name"}, sortQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, []int64{3, 1, 2}, cursorIds(page.List))
+	page, err = PageQuery[searchUser](db, &modelx.Pagination{PageSize: 2, SortBY: "tag.name", Descending: true}, sortQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, []int64{1, 2}, cursorIds(page.List))
+	page, err = PageQuery[searchUser](db, &modelx.Pagination{PageSize: 10}, sortQuery{Tag: sortTag{Name: "desc"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, cursorIds(page.List))
+
+	page, err = PageQuery[searchUser](db, &modelx.Pagination{PageSize: 2, PageIndex: 3}, query{Tag: tagQuery{Name: "a"}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Empty(t, page.List)
+
+	page, err = PageQuery[searchUser](db, &modelx.Pagination{PageSize: 5, PageIndex: 2}, nil, WithSkipShortCount())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), page.Total)
+	assert.Len(t, page.List, 2)
+}
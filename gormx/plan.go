@@ -0,0 +1,314 @@
+package gormx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/qiaogw/sub-sdk/searchtag"
+)
+
+// searchPlans 缓存已编译的检索计划，key 为 planKey
+var searchPlans sync.Map
+
+type (
+	// planKey 检索计划缓存键，同一结构体在不同方言下生成的 SQL 不同
+	planKey struct {
+		typ     reflect.Type
+		dialect string
+	}
+
+	// searchPlan 查询结构体编译后的检索计划，字段按声明顺序排列（嵌套结构体已展开）
+	searchPlan struct {
+		fields []*searchField
+	}
+
+	// joinScope 关联子结构体的表名解析范围，顶层结构体为零值
+	joinScope struct {
+		table string // 关联表名
+		alias string // 关联表别名，未设置时与表名相同
+	}
+
+	// searchField 单个 search 字段的预编译信息
+	searchField struct {
+		name   string
+		json   string
+		typ    reflect.Type
+		index  []int
+		tag    *resolveSearchTag
+		column string
+		// build 根据字段值生成条件片段，固定形态的 SQL 在编译时已生成
+		build func(value reflect.Value) (string, []interface{}, bool)
+		// join 关联字段的 JOIN 语句及子结构体计划
+		join string
+		sub  *searchPlan
+	}
+)
+
+// PrepareSearchQuery 预编译并缓存查询结构体的检索计划，可在启动时调用以提前发现错误标签
+func PrepareSearchQuery(driver string, q interface{}) error {
+	typ := reflect.TypeOf(q)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return fmt.Errorf("search 查询对象必须为结构体: %v", typ)
+	}
+	name, d := resolveDialect(driver)
+	_, err := loadSearchPlan(name, d, typ)
+	return err
+}
+
+// loadSearchPlan 获取结构体的检索计划，不存在时编译并缓存
+func loadSearchPlan(name string, d SearchDialect, typ reflect.Type) (*searchPlan, error) {
+	key := planKey{typ: typ, dialect: name}
+	if p, ok := searchPlans.Load(key); ok {
+		return p.(*searchPlan), nil
+	}
+	p, err := compileSearchPlan(d, typ, joinScope{})
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := searchPlans.LoadOrStore(key, p)
+	return actual.(*searchPlan), nil
+}
+
+// compileSearchPlan 解析结构体全部 search 标签并生成检索计划
+func compileSearchPlan(d SearchDialect, typ reflect.Type, scope joinScope) (*searchPlan, error) {
+	p := &searchPlan{}
+	if err := p.compileFields(d, typ, nil, scope); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compileFields 按声明顺序编译字段，未设置 search 标签的结构体字段递归展开
+func (p *searchPlan) compileFields(d SearchDialect, typ reflect.Type, parent []int, scope joinScope) error {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		index := append(append(make([]int, 0, len(parent)+1), parent...), i)
+		tag, ok := sf.Tag.Lookup(FromQueryTag)
+		if !ok {
+			//递归解析嵌套结构体
+			if sf.Type.Kind() == reflect.Struct {
+				if err := p.compileFields(d, sf.Type, index, scope); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		// 跳过无效 tag
+		if tag == "-" {
+			continue
+		}
+		f, err := compileSearchField(d, sf, tag, scope)
+		if err != nil {
+			return err
+		}
+		if f == nil {
+			continue
+		}
+		f.index = index
+		p.fields = append(p.fields, f)
+	}
+	return nil
+}
+
+// compileSearchField 编译单个字段，未设置 type 的字段仅用于排序映射，不生成条件
+func compileSearchField(d SearchDialect, sf reflect.StructField, tag string, scope joinScope) (*searchField, error) {
+	t := makeTag(tag)
+	if t.Type == "" && t.Column == "" {
+		return nil, nil
+	}
+	f := &searchField{name: sf.Name, json: jsonName(sf), typ: sf.Type, tag: t}
+	switch t.Type {
+	case LeftJoin, InnerJoins, RightJoin:
+		if t.Join == "" {
+			return nil, fmt.Errorf("search 标签错误 %s: 关联缺少 join", sf.Name)
+		}
+		if len(t.On) == 0 {
+			return nil, fmt.Errorf("search 标签错误 %s: 关联缺少 on", sf.Name)
+		}
+		if sf.Type.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("search 标签错误 %s: 关联字段必须为结构体", sf.Name)
+		}
+		alias := t.Alias
+		if alias == "" {
+			alias = t.Join
+		}
+		// on 左侧为关联表（别名），右侧为上一级表，嵌套关联时为上一级关联的别名
+		left := scope.resolveTable(t.Table)
+		on := make([]string, 0, len(t.On)+len(t.Cond))
+		for _, pair := range t.On {
+			if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+				return nil, fmt.Errorf("search 标签错误 %s: 关联 on 须为 on:关联表字段:主表字段", sf.Name)
+			}
+			on = append(on, fmt.Sprintf("%s = %s", columnRef(d, alias, pair[0]), columnRef(d, left, pair[1])))
+		}
+		on = append(on, t.Cond...)
+		f.join = d.Join(t.Type, t.Join, t.Alias, on)
+		sub, err := compileSearchPlan(d, sf.Type, joinScope{table: t.Join, alias: alias})
+		if err != nil {
+			return nil, err
+		}
+		f.sub = sub
+		return f, nil
+	}
+	if t.Column == "" {
+		return nil, fmt.Errorf("search 标签错误 %s: 缺少 column", sf.Name)
+	}
+	if t.Group != "" && strings.Contains("."+t.Group+".", "..") {
+		return nil, fmt.Errorf("search 标签错误 %s: group 格式不正确 %q", sf.Name, t.Group)
+	}
+	if t.Logic != "" && t.Logic != LogicAnd && t.Logic != LogicOr {
+		return nil, fmt.Errorf("search 标签错误 %s: 不支持的 logic %q", sf.Name, t.Logic)
+	}
+	f.column = columnRef(d, scope.resolveTable(t.Table), t.Column)
+	if t.Type == "" {
+		return f, nil
+	}
+	build, err := compileSearchExpr(d, t, f.column)
+	if err != nil {
+		return nil, fmt.Errorf("search 标签错误 %s: %v", sf.Name, err)
+	}
+	f.build = build
+	return f, nil
+}
+
+// compileSearchExpr 生成字段的条件构造函数，参数个数固定的操作符在此处预生成 SQL
+func compileSearchExpr(d SearchDialect, t *resolveSearchTag, columnRef string) (func(reflect.Value) (string, []interface{}, bool), error) {
+	fixed := func(sql string) func(reflect.Value) (string, []interface{}, bool) {
+		return func(v reflect.Value) (string, []interface{}, bool) {
+			return sql, []interface{}{v.Interface()}, true
+		}
+	}
+	// like 用户输入中的通配符按字面量匹配
+	like := func(sql, prefix, suffix string) func(reflect.Value) (string, []interface{}, bool) {
+		return func(v reflect.Value) (string, []interface{}, bool) {
+			return sql, []interface{}{prefix + d.EscapeLike(v.String()) + suffix}, true
+		}
+	}
+	driver := d.Name()
+	switch t.Type {
+	case Order:
+		return nil, nil
+	case Exact:
+		return fixed(fmt.Sprintf("%s = ?", columnRef)), nil
+	case IExact:
+		return fixed(fmt.Sprintf("LOWER(%s) = LOWER(?)", columnRef)), nil
+	case NotEqual:
+		return fixed(fmt.Sprintf("%s <> ?", columnRef)), nil
+	case Greater:
+		return fixed(fmt.Sprintf("%s > ?", columnRef)), nil
+	case GreaterEq:
+		return fixed(fmt.Sprintf("%s >= ?", columnRef)), nil
+	case Less:
+		return fixed(fmt.Sprintf("%s < ?", columnRef)), nil
+	case LessEq:
+		return fixed(fmt.Sprintf("%s <= ?", columnRef)), nil
+	case In:
+		return fixed(fmt.Sprintf("%s in (?)", columnRef)), nil
+	case NotIn:
+		return fixed(fmt.Sprintf("%s not in (?)", columnRef)), nil
+	case Contains, IContains:
+		return like(d.Like(columnRef, t.Type == IContains), "%", "%"), nil
+	case StartsWith, IStartsWith:
+		return like(d.Like(columnRef, t.Type == IStartsWith), "", "%"), nil
+	case EndsWith, IEndsWith:
+		return like(d.Like(columnRef, t.Type == IEndsWith), "%", ""), nil
+	case IsNull, NotNull:
+		sql := fmt.Sprintf("%s is null", columnRef)
+		if t.Type == NotNull {
+			sql = fmt.Sprintf("%s is not null", columnRef)
+		}
+		return func(reflect.Value) (string, []interface{}, bool) {
+			return sql, make([]interface{}, 0), true
+		}, nil
+	case Between:
+		return func(v reflect.Value) (string, []interface{}, bool) {
+			return betweenExpr(columnRef, v)
+		}, nil
+	case JsonEqual, JsonContains:
+		if t.Path != "" || t.Type == JsonEqual {
+			if _, ok := jsonPath(driver, t.Path); !ok {
+				return nil, fmt.Errorf("JSON 路径不正确 %q", t.Path)
+			}
+		}
+		if t.Type == JsonEqual {
+			return func(v reflect.Value) (string, []interface{}, bool) {
+				return jsonEqualExpr(driver, columnRef, t.Path, v)
+			}, nil
+		}
+		return func(v reflect.Value) (string, []interface{}, bool) {
+			return jsonContainsExpr(driver, columnRef, t.Path, v)
+		}, nil
+	case ArrayContains, ArrayOverlap:
+		return func(v reflect.Value) (string, []interface{}, bool) {
+			return arrayExpr(driver, t.Type, columnRef, v)
+		}, nil
+	}
+	return nil, fmt.Errorf("不支持的查询类型 %q", t.Type)
+}
+
+// jsonName 字段的 json 名称，未设置时使用字段名
+func jsonName(sf reflect.StructField) string {
+	return searchtag.JSONName(sf)
+}
+
+// resolveTable 关联子结构体中未设置 table 或 table 为关联表名的字段指向关联别名
+func (s joinScope) resolveTable(table string) string {
+	if s.alias == "" {
+		return table
+	}
+	if table == "" || table == s.table {
+		return s.alias
+	}
+	return table
+}
+
+// columnRef 生成带表名的字段引用，未指定表名时仅引用字段
+func columnRef(d SearchDialect, table, column string) string {
+	if table == "" {
+		return d.Quote(column)
+	}
+	return d.Quote(table) + "." + d.Quote(column)
+}
+
+// bind 将结构体取值绑定到检索计划，并按字段顺序写入 condition
+func (p *searchPlan) bind(value reflect.Value, condition Condition) {
+	groups := &searchGroups{}
+	for _, f := range p.fields {
+		fv := value.FieldByIndex(f.index)
+		// 跳过无效空字段
+		if fv.IsZero() {
+			continue
+		}
+		switch {
+		case f.sub != nil:
+			join := condition.SetJoinOn(f.tag.Type, f.join)
+			f.sub.bind(fv, join)
+		case f.tag.Type == "":
+			continue
+		case f.tag.Type == Order:
+			orderValue := strings.ToLower(fv.String())
+			if orderValue == OrderDesc || orderValue == OrderAsc {
+				condition.SetOrder(fmt.Sprintf("%s %s", f.column, orderValue))
+			}
+		default:
+			expr, args, ok := f.build(fv)
+			if !ok {
+				continue
+			}
+			switch {
+			case f.tag.Group != "":
+				groups.add(f.tag.Group, f.tag.Logic, expr, args)
+			case f.tag.Logic == LogicOr:
+				condition.SetOr(expr, args)
+			default:
+				condition.SetWhere(expr, args)
+			}
+		}
+	}
+	groups.flush(condition)
+}
@@ -1,10 +1,72 @@
 package plugins
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"sort"
+	"sync"
 
-func InitPlugins(db *gorm.DB) error {
-	if err := db.Use(&TracingPlugin{}); err != nil {
-		return err
+	"gorm.io/gorm"
+)
+
+// Tracing 链路追踪插件的注册名
+const Tracing = "tracing"
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]gorm.Plugin{Tracing: &TracingPlugin{}}
+	// defaults 未指定插件时启用的插件
+	defaults = []string{Tracing}
+)
+
+// Register 以 name 注册插件，供 configx.DbConf.Plugins 按名称选择，重复注册时覆盖
+func Register(name string, plugin gorm.Plugin) {
+	if plugin == nil {
+		panic("plugins: Register plugin is nil")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = plugin
+}
+
+// Names 返回已注册的插件名
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return keys(registry)
+}
+
+// Use 按名称启用已注册的插件，names 为空时启用默认插件，db 中已启用的插件跳过
+func Use(db *gorm.DB, names ...string) error {
+	if len(names) == 0 {
+		names = defaults
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, name := range names {
+		plugin, ok := registry[name]
+		if !ok {
+			return fmt.Errorf("未注册的插件：%s，已注册：%v", name, keys(registry))
+		}
+		if _, ok := db.Config.Plugins[plugin.Name()]; ok {
+			continue
+		}
+		if err := db.Use(plugin); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// InitPlugins 启用默认插件
+func InitPlugins(db *gorm.DB) error {
+	return Use(db)
+}
+
+func keys(m map[string]gorm.Plugin) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
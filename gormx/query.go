@@ -1,37 +1,52 @@
 package gormx
 
 import (
-	"fmt"
 	"reflect"
-	"strings"
 )
 
 const (
 	FromQueryTag = "search"   // FromQueryTag tag标记
 	Mysql        = "mysql"    // Mysql 数据库标识
 	Postgres     = "postgres" // Postgres 数据库标识
+	Sqlite       = "sqlite"   // Sqlite 数据库标识
 )
 const (
 	Exact       = "exact"       // 精确匹配，相当于 SQL 的 `=`
-	IExact      = "iexact"      // 不区分大小写的精确匹配（PostgreSQL 适用）
+	IExact      = "iexact"      // 不区分大小写的精确匹配，SQL: `LOWER(column) = LOWER(value)`
+	NotEqual    = "ne"          // 不等于，SQL: `column <> value`
 	Contains    = "contains"    // 包含匹配，相当于 SQL 的 `LIKE %xxx%`
-	IContains   = "icontains"   // 不区分大小写的包含匹配（PostgreSQL `ILIKE %xxx%`）
+	IContains   = "icontains"   // 不区分大小写的包含匹配，由方言决定（PostgreSQL `ILIKE %xxx%`）
 	Greater     = "gt"          // 大于 (`>`)，SQL: `column > value`
 	GreaterEq   = "gte"         // 大于等于 (`>=`)，SQL: `column >= value`
 	Less        = "lt"          // 小于 (`<`)，SQL: `column < value`
 	LessEq      = "lte"         // 小于等于 (`<=`)，SQL: `column <= value`
 	StartsWith  = "startswith"  // 以某值开头，SQL: `LIKE 'xxx%'`
-	IStartsWith = "istartswith" // 不区分大小写的以某值开头（PostgreSQL `ILIKE 'xxx%'`）
+	IStartsWith = "istartswith" // 不区分大小写的以某值开头，由方言决定（PostgreSQL `ILIKE 'xxx%'`）
 	EndsWith    = "endswith"    // 以某值结尾，SQL: `LIKE '%xxx'`
-	IEndsWith   = "iendswith"   // 不区分大小写的以某值结尾（PostgreSQL `ILIKE '%xxx'`）
+	IEndsWith   = "iendswith"   // 不区分大小写的以某值结尾，由方言决定（PostgreSQL `ILIKE '%xxx'`）
 	In          = "in"          // IN 查询，SQL: `IN (val1, val2, val3, ...)`
+	NotIn       = "notin"       // NOT IN 查询，SQL: `NOT IN (val1, val2, val3, ...)`
+	Between     = "between"     // 区间查询，值为两个元素的切片或 Range，SQL: `BETWEEN ? AND ?`
 	IsNull      = "isnull"      // 是否为空，SQL: `IS NULL`
+	NotNull     = "notnull"     // 是否非空，SQL: `IS NOT NULL`
 	Order       = "order"       // 排序，SQL: `ORDER BY column ASC/DESC`
 	LeftJoin    = "left"        // 左连接，SQL: `LEFT JOIN table ON condition`
-	InnerJoins  = "inner"       // 左连接，SQL: `LEFT JOIN table ON condition`
+	InnerJoins  = "inner"       // 内连接，SQL: `INNER JOIN table ON condition`
+	RightJoin   = "right"       // 右连接，SQL: `RIGHT JOIN table ON condition`
+	// JSON 与数组
+	JsonEqual     = "jsoneq"        // JSON 路径取值等于，需配合 path，e.g. path:a.b
+	JsonContains  = "jsoncontains"  // JSON 包含，MySQL `JSON_CONTAINS`，Postgres `@>`
+	ArrayContains = "arraycontains" // 数组包含全部元素，Postgres `@>`，其它数据库按 JSON 数组处理
+	ArrayOverlap  = "overlap"       // 数组存在交集，Postgres `&&`，其它数据库按 JSON 数组处理
 	// 排序方式
 	OrderAsc  = "asc"  // 升序排序，SQL: `ORDER BY column ASC`
 	OrderDesc = "desc" // 降序排序，SQL: `ORDER BY column DESC`
+	// 空值排序位置，用于 OrderBy，e.g. sortBy=createdAt:nullslast
+	NullsFirst = "nullsfirst" // 空值在前，SQL: `NULLS FIRST`
+	NullsLast  = "nullslast"  // 空值在后，SQL: `NULLS LAST`
+	// 分组连接方式
+	LogicAnd = "and" // 组内条件以 AND 连接
+	LogicOr  = "or"  // 组内条件以 OR 连接
 )
 
 // ResolveSearchQuery 解析
@@ -42,130 +57,48 @@ const (
  *	lt / lte 小于 / 小于等于
  *	startswith / istartswith 以…起始
  *	endswith / iendswith 以…结束
- *	in
- *	isnull
+ *	ne 不等于
+ *	in / notin
+ *	between 区间		e.g. [from, to] 或 Range{From, To}
+ *	isnull / notnull
+ *	jsoneq / jsoncontains JSON 字段	e.g. search:"type:jsoneq;column:attrs;path:profile.name"
+ *	arraycontains / overlap 数组字段
  *  order 排序		e.g. order[key]=desc     order[key]=asc
+ *
+ *  group 分组		e.g. search:"type:contains;column:name;group:kw;logic:or"
+ *		相同 group 的字段以括号包裹，组内按 logic 连接（默认 and），组与组之间为 AND；
+ *		group 以 "." 分隔表示嵌套分组，如 group:kw.detail；
+ *		未设置 group 但 logic:or 的字段写入 Or 条件
+ *
+ *  join 关联		e.g. search:"type:left;table:dept;join:dept;alias:p;on:id:parent_id;on:tenant_id:tenant_id;cond:p.deleted_at IS NULL"
+ *		type 可为 left/inner/right，多个 on 以 AND 连接，cond 为附加在 ON 中的固定条件；
+ *		关联子结构体中未设置 table 或 table 为关联表名的字段解析为别名，可继续嵌套关联
+ *
+ *  结构体首次解析时编译为检索计划并缓存，标签错误（如关联缺少 on）返回 error
+ *  driver 用于查找 SearchDialect，未注册的驱动按 MySQL 处理
  */
-func ResolveSearchQuery(driver string, q interface{}, condition Condition) {
-	qType := reflect.TypeOf(q)
-	qValue := reflect.ValueOf(q)
-	var t *resolveSearchTag
-	var tag string
-	var ok bool
-	for i := 0; i < qType.NumField(); i++ {
-		tag, ok = "", false
-		tag, ok = qType.Field(i).Tag.Lookup(FromQueryTag)
-		if !ok {
-			//递归解析嵌套结构体
-			ResolveSearchQuery(driver, qValue.Field(i).Interface(), condition)
-			continue
-		}
-		// 跳过无效 tag
-		if tag == "-" {
-			continue
-		}
-
-		// 跳过无效空字段
-		if qValue.Field(i).IsZero() {
-			continue
-		}
-		t = makeTag(tag)
-		// 解析查询类型
-		columnRef := formatColumn(driver, t.Table, t.Column)
-		switch t.Type {
-		//fix Postgres 双引号
-		case LeftJoin:
-			//左关联
-			joinSQL := formatJoinSQL(driver, t.Join, t.On, t.Table)
-			join := condition.SetJoinOn(t.Type, joinSQL)
-			ResolveSearchQuery(driver, qValue.Field(i).Interface(), join)
-		case InnerJoins:
-			//关联
-			joinSQL := formatInnerJoins(driver, t.Join, t.On, t.Table)
-			join := condition.SetJoinOn(t.Type, joinSQL)
-			ResolveSearchQuery(driver, qValue.Field(i).Interface(), join)
-		case Exact, IExact:
-			condition.SetWhere(fmt.Sprintf("%s = ?", columnRef), []interface{}{qValue.Field(i).Interface()})
-
-		case Contains, IContains:
-			if driver == Postgres {
-				condition.SetWhere(fmt.Sprintf(`%s ILIKE ?`, columnRef), []interface{}{"%" + qValue.Field(i).String() + "%"})
-			} else {
-				condition.SetWhere(fmt.Sprintf(`%s LIKE ?`, columnRef), []interface{}{"%" + qValue.Field(i).String() + "%"})
-			}
-		case Greater:
-			condition.SetWhere(fmt.Sprintf("%s > ?", columnRef), []interface{}{qValue.Field(i).Interface()})
-
-		case GreaterEq:
-			condition.SetWhere(fmt.Sprintf("%s >= ?", columnRef), []interface{}{qValue.Field(i).Interface()})
-
-		case Less:
-			condition.SetWhere(fmt.Sprintf("%s < ?", columnRef), []interface{}{qValue.Field(i).Interface()})
-
-		case LessEq:
-			condition.SetWhere(fmt.Sprintf("%s <= ?", columnRef), []interface{}{qValue.Field(i).Interface()})
-
-		case StartsWith, IStartsWith:
-			if driver == Postgres {
-				condition.SetWhere(fmt.Sprintf(`%s ILIKE ?`, columnRef), []interface{}{qValue.Field(i).String() + "%"})
-			} else {
-				condition.SetWhere(fmt.Sprintf(`%s LIKE ?`, columnRef), []interface{}{qValue.Field(i).String() + "%"})
-			}
-		case EndsWith, IEndsWith:
-			if driver == Postgres {
-				condition.SetWhere(fmt.Sprintf(`%s ILIKE ?`, columnRef), []interface{}{"%" + qValue.Field(i).String()})
-			} else {
-				condition.SetWhere(fmt.Sprintf(`%s LIKE ?`, columnRef), []interface{}{"%" + qValue.Field(i).String()})
-			}
-		case In:
-			condition.SetWhere(fmt.Sprintf("%s in (?)", columnRef), []interface{}{qValue.Field(i).Interface()})
-
-		case IsNull:
-			if !(qValue.Field(i).IsZero() && qValue.Field(i).IsNil()) {
-				condition.SetWhere(fmt.Sprintf("%s is null", columnRef), make([]interface{}, 0))
-			}
+func ResolveSearchQuery(driver string, q interface{}, condition Condition) error {
+	name, d := resolveDialect(driver)
+	return resolveSearchQuery(name, d, q, condition)
+}
 
-		case Order:
-			orderValue := strings.ToLower(qValue.Field(i).String())
-			if orderValue == "desc" || orderValue == "asc" {
-				condition.SetOrder(fmt.Sprintf("%s %s", columnRef, orderValue))
-			}
+// resolveSearchQuery 使用指定方言解析查询结构体
+func resolveSearchQuery(name string, d SearchDialect, q interface{}, condition Condition) error {
+	value := reflect.ValueOf(q)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
 		}
+		value = value.Elem()
 	}
-}
-
-// 处理数据库字段格式（Postgres 使用双引号，MySQL 使用反引号）
-func formatColumn(driver, table, column string) string {
-	if driver == Postgres {
-		return fmt.Sprintf(`"%s"."%s"`, table, column)
+	if value.Kind() != reflect.Struct {
+		return nil
 	}
-	return fmt.Sprintf("`%s`.`%s`", table, column)
-}
-
-// 生成 JOIN 语句
-func formatJoinSQL(driver, joinTable string, on []string, table string) string {
-	if driver == Postgres {
-		return fmt.Sprintf(
-			`LEFT JOIN "%s" ON "%s"."%s" = "%s"."%s"`,
-			joinTable, joinTable, on[0], table, on[1],
-		)
-	}
-	return fmt.Sprintf(
-		"LEFT JOIN `%s` ON `%s`.`%s` = `%s`.`%s`",
-		joinTable, joinTable, on[0], table, on[1],
-	)
-}
-
-// 生成 JOIN 语句
-func formatInnerJoins(driver, joinTable string, on []string, table string) string {
-	if driver == Postgres {
-		return fmt.Sprintf(
-			`INNER JOIN "%s" ON "%s"."%s" = "%s"."%s"`,
-			joinTable, joinTable, on[0], table, on[1],
-		)
+	// 结构体类型首次使用时编译检索计划并缓存，之后只做取值绑定
+	plan, err := loadSearchPlan(name, d, value.Type())
+	if err != nil {
+		return err
 	}
-	return fmt.Sprintf(
-		"INNER JOIN `%s` ON `%s`.`%s` = `%s`.`%s`",
-		joinTable, joinTable, on[0], table, on[1],
-	)
+	plan.bind(value, condition)
+	return nil
 }
@@ -0,0 +1,223 @@
+package gormx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type searchUser struct {
+	Id     int64
+	Name   string
+	Code   string
+	Status int64
+}
+
+func (searchUser) TableName() string {
+	return "user"
+}
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DryRun: true})
+	assert.Nil(t, err)
+	return db
+}
+
+func TestMakeConditionGroup(t *testing.T) {
+	type query struct {
+		Name   string `search:"type:contains;table:user;column:name;group:kw;logic:or"`
+		Code   string `search:"type:contains;table:user;column:code;group:kw;logic:or"`
+		Status int64  `search:"type:exact;table:user;column:status"`
+	}
+	db := newDryRunDB(t)
+	stmt := db.Scopes(MakeCondition(query{Name: "a", Code: "a", Status: 1}, "sqlite")).
+		Find(&[]searchUser{}).Statement
+	assert.Contains(t, stmt.SQL.String(), "(`user`.`name` LIKE ? ESCAPE '\\' OR `user`.`code` LIKE ? ESCAPE '\\')")
+	assert.Contains(t, stmt.SQL.String(), "`user`.`status` = ?")
+	assert.Len(t, stmt.Vars, 3)
+}
+
+func TestMakeConditionNestedGroup(t *testing.T) {
+	type query struct {
+		Name  string `search:"type:exact;table:user;column:name;group:kw;logic:or"`
+		Code  string `search:"type:exact;table:user;column:code;group:kw.inner"`
+		Other string `search:"type:exact;table:user;column:other;group:kw.inner"`
+	}
+	db := newDryRunDB(t)
+	stmt := db.Scopes(MakeCondition(query{Name: "a", Code: "b", Other: "c"}, "sqlite")).
+		Find(&[]searchUser{}).Statement
+	assert.Contains(t, stmt.SQL.String(),
+		"(`user`.`name` = ? OR (`user`.`code` = ? AND `user`.`other` = ?))")
+	assert.Equal(t, []interface{}{"a", "b", "c"}, stmt.Vars)
+}
+
+func TestMakeConditionOperators(t *testing.T) {
+	type query struct {
+		Name    string       `search:"type:iexact;table:user;column:name"`
+		Code    string       `search:"type:ne;table:user;column:code"`
+		Status  []int64      `search:"type:notin;table:user;column:status"`
+		Id      Range[int64] `search:"type:between;table:user;column:id"`
+		Deleted bool         `search:"type:notnull;table:user;column:deleted_at"`
+	}
+	db := newDryRunDB(t)
+	stmt := db.Scopes(MakeCondition(query{
+		Name:    "A",
+		Code:    "b",
+		Status:  []int64{1, 2},
+		Id:      Range[int64]{From: 1, To: 9},
+		Deleted: true,
+	}, "sqlite")).Find(&[]searchUser{}).Statement
+	sql := stmt.SQL.String()
+	assert.Contains(t, sql, "LOWER(`user`.`name`) = LOWER(?)")
+	assert.Contains(t, sql, "`user`.`code` <> ?")
+	assert.Contains(t, sql, "`user`.`status` not in (?,?)")
+	assert.Contains(t, sql, "`user`.`id` BETWEEN ? AND ?")
+	assert.Contains(t, sql, "`user`.`deleted_at` is not null")
+}
+
+func TestJsonPath(t *testing.T) {
+	p, ok := jsonPath(Mysql, "items.0.name")
+	assert.True(t, ok)
+	assert.Equal(t, "'$.items[0].name'", p)
+	p, ok = jsonPath(Postgres, "items.0.name")
+	assert.True(t, ok)
+	assert.Equal(t, "'{items,0,name}'", p)
+	_, ok = jsonPath(Mysql, "a');drop")
+	assert.False(t, ok)
+}
+
+func TestMakeConditionStableSQL(t *testing.T) {
+	type dept struct {
+		Name string `search:"type:contains;table:dept;column:name"`
+	}
+	type query struct {
+		Dept   dept   `search:"type:left;table:user;join:dept;on:id:dept_id"`
+		Status int64  `search:"type:exact;table:user;column:status"`
+		Name   string `search:"type:contains;table:user;column:name"`
+		Code   string `search:"type:gte;table:user;column:code"`
+		Sort   string `search:"type:order;table:user;column:sort"`
+	}
+	q := query{Dept: dept{Name: "d"}, Status: 1, Name: "n", Code: "c", Sort: "desc"}
+	want := "SELECT `user`.`id`,`user`.`name`,`user`.`code`,`user`.`status` FROM `user` " +
+		"LEFT JOIN `dept` ON `dept`.`id` = `user`.`dept_id` " +
+		"WHERE `dept`.`name` LIKE ? ESCAPE '\\' AND `user`.`status` = ? AND `user`.`name` LIKE ? ESCAPE '\\' " +
+		"AND `user`.`code` >= ? " +
+		"ORDER BY `user`.`sort` desc"
+	for i := 0; i < 5; i++ {
+		stmt := newDryRunDB(t).Scopes(MakeCondition(q, "sqlite")).Find(&[]searchUser{}).Statement
+		assert.Equal(t, want, stmt.SQL.String())
+		assert.Equal(t, []interface{}{"%d%", int64(1), "%n%", "c"}, stmt.Vars)
+	}
+}
+
+func TestMakeConditionMalformedTag(t *testing.T) {
+	type dept struct {
+		Name string `search:"type:contains;table:dept;column:name"`
+	}
+	type query struct {
+		Dept dept `search:"type:left;table:user;join:dept"`
+	}
+	assert.NotNil(t, PrepareSearchQuery("sqlite", query{}))
+	err := newDryRunDB(t).Scopes(MakeCondition(query{Dept: dept{Name: "d"}}, "sqlite")).
+		Find(&[]searchUser{}).Error
+	assert.NotNil(t, err)
+
+	type unknown struct {
+		Name string `search:"type:like;column:name"`
+	}
+	assert.NotNil(t, PrepareSearchQuery("sqlite", unknown{}))
+}
+
+func TestSearchDialect(t *testing.T) {
+	type query struct {
+		Name string `search:"type:icontains;table:user;column:name"`
+		Code string `search:"type:exact;column:code"`
+	}
+	RegisterSearchDialect("kingbase", PostgresDialect{})
+	condition := &GormCondition{}
+	err := ResolveSearchQuery("kingbase", query{Name: "50%_a", Code: "c"}, condition)
+	assert.Nil(t, err)
+	assert.Equal(t, []GormClause{
+		{Query: `"user"."name" ILIKE ?`, Args: []interface{}{`%50\%\_a%`}},
+		{Query: `"code" = ?`, Args: []interface{}{"c"}},
+	}, condition.Where)
+
+	condition = &GormCondition{}
+	err = ResolveSearchQuery(Mysql, query{Name: "a"}, condition)
+	assert.Nil(t, err)
+	assert.Equal(t, "LOWER(`user`.`name`) LIKE LOWER(?)", condition.Where[0].Query)
+
+	condition = &GormCondition{}
+	err = ResolveSearchQuery(Sqlite, query{Name: "a"}, condition)
+	assert.Nil(t, err)
+	assert.Equal(t, "LOWER(`user`.`name`) LIKE LOWER(?) ESCAPE '\\'", condition.Where[0].Query)
+}
+
+func TestMakeConditionAliasJoin(t *testing.T) {
+	type grand struct {
+		Name string `search:"type:exact;column:name"`
+	}
+	type parent struct {
+		Name  string `search:"type:contains;table:dept;column:name"`
+		Grand grand  `search:"type:right;table:dept;join:dept;alias:g;on:id:parent_id"`
+	}
+	type query struct {
+		Parent parent `search:"type:inner;table:dept;join:dept;alias:p;on:id:parent_id;on:tenant_id:tenant_id;cond:p.deleted_at IS NULL"`
+		Name   string `search:"type:exact;table:dept;column:name"`
+	}
+	condition := &GormCondition{}
+	err := ResolveSearchQuery(Postgres, query{
+		Parent: parent{Name: "p", Grand: grand{Name: "g"}},
+		Name:   "d",
+	}, condition)
+	assert.Nil(t, err)
+	assert.Len(t, condition.Join, 2)
+	assert.Equal(t, `INNER JOIN "dept" AS "p" ON "p"."id" = "dept"."parent_id" AND "p"."tenant_id" = "dept"."tenant_id" AND p.deleted_at IS NULL`,
+		condition.Join[0].JoinOn)
+	assert.Equal(t, `"p"."name" LIKE ?`, condition.Join[0].Where[0].Query)
+	assert.Equal(t, `RIGHT JOIN "dept" AS "g" ON "g"."id" = "p"."parent_id"`, condition.Join[1].JoinOn)
+	assert.Equal(t, `"g"."name" = ?`, condition.Join[1].Where[0].Query)
+	assert.Equal(t, `"dept"."name" = ?`, condition.Where[0].Query)
+}
+
+func TestOrderBy(t *testing.T) {
+	type dept struct {
+		Name string `json:"name" search:"type:exact;column:name"`
+	}
+	type query struct {
+		Status int64  `json:"status" search:"type:exact;table:user;column:status"`
+		Code   string `json:"code" search:"table:user;column:code"`
+		Dept   dept   `json:"dept" search:"type:left;table:user;join:dept;on:id:dept_id"`
+	}
+	db := newDryRunDB(t)
+	stmt := db.Scopes(OrderBy(query{}, "code, -dept.name:nullslast", false), MakeCondition(query{Dept: dept{Name: "a"}}, Sqlite)).
+		Find(&[]searchUser{}).Statement
+	assert.Nil(t, stmt.Error)
+	assert.Equal(t, "SELECT `user`.`id`,`user`.`name`,`user`.`code`,`user`.`status` FROM `user` "+
+		"LEFT JOIN `dept` ON `dept`.`id` = `user`.`dept_id` WHERE `dept`.`name` = ? "+
+		"ORDER BY `user`.`code`,`dept`.`name` DESC NULLS LAST,`user`.`id` DESC", stmt.SQL.String())
+
+	assert.Equal(t, "`user`.`status` IS NULL DESC, `user`.`status`",
+		MysqlDialect{}.OrderBy("`user`.`status`", false, NullsFirst))
+
+	for _, sortBy := range []string{"password", "status:first", "dept", "id; DROP TABLE user"} {
+		stmt = newDryRunDB(t).Scopes(OrderBy(query{}, sortBy, false)).Find(&[]searchUser{}).Statement
+		var codeErr *errx.CodeError
+		assert.True(t, errors.As(stmt.Error, &codeErr), sortBy)
+	}
+	assert.Equal(t, "code", GetSortBy(&query{}, "code"))
+	column, err := SortColumn(&query{}, "dept.name")
+	assert.Nil(t, err)
+	assert.Equal(t, "name", column)
+	_, err = SortColumn(&query{}, "password")
+	assert.NotNil(t, err)
+	type badQuery struct {
+		Name string `json:"name" search:"type:exact"`
+	}
+	_, err = SortColumn(badQuery{}, "name")
+	assert.NotNil(t, err)
+}
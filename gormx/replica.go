@@ -0,0 +1,282 @@
+package gormx
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/qiaogw/sub-sdk/gormx/configx"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"gorm.io/gorm"
+)
+
+const (
+	// ReplicaRoundRobin 在健康副本间轮询
+	ReplicaRoundRobin = "roundrobin"
+	// ReplicaLeastLatency 选择健康检查延迟最低的副本
+	ReplicaLeastLatency = "leastlatency"
+
+	defaultReadYourWrites = time.Second
+	defaultCheckInterval  = 5 * time.Second
+	defaultCheckTimeout   = time.Second
+	// latencyDecay 延迟指数加权平均中历史值的权重
+	latencyDecay = 0.7
+)
+
+type (
+	// ReplicaOptions 只读副本的选择策略与健康检查参数
+	ReplicaOptions struct {
+		Policy         string        // ReplicaRoundRobin 或 ReplicaLeastLatency，默认轮询
+		ReadYourWrites time.Duration // 写入后读取固定到主库的时间窗口，默认 1s
+		CheckInterval  time.Duration // 健康检查间隔，默认 5s
+		CheckTimeout   time.Duration // 单次检查超时，默认 1s
+	}
+
+	// ReplicaSet 只读副本集合，定期检查副本健康，检查失败的副本暂停分发，恢复后重新加入
+	// 通过 WithReplicas 绑定主库时，副本启用主库已注册的插件（数据权限、租户等），读取副本时同样生效
+	ReplicaSet struct {
+		replicas []*replica
+		options  ReplicaOptions
+		next     atomic.Uint64
+		done     chan struct{}
+		once     sync.Once
+		mu       sync.Mutex
+	}
+
+	// ReplicaStat 副本状态
+	ReplicaStat struct {
+		Healthy bool
+		Latency time.Duration // 健康检查延迟的指数加权平均
+	}
+
+	replica struct {
+		db      *gorm.DB
+		healthy atomic.Bool
+		latency atomic.Int64
+	}
+
+	// primaryCtxKey 上下文中固定读取主库的标记
+	primaryCtxKey struct{}
+	// writeCtxKey 上下文中记录最近写入时间的键
+	writeCtxKey struct{}
+	// writeState 最近写入时间（纳秒）
+	writeState struct {
+		last atomic.Int64
+	}
+)
+
+// NewReplicaSet 创建只读副本集合，同步完成首次健康检查后在后台定期检查，不再使用时调用 Close
+func NewReplicaSet(dbs []*gorm.DB, opts ReplicaOptions) *ReplicaSet {
+	if opts.ReadYourWrites <= 0 {
+		opts.ReadYourWrites = defaultReadYourWrites
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = defaultCheckInterval
+	}
+	if opts.CheckTimeout <= 0 {
+		opts.CheckTimeout = defaultCheckTimeout
+	}
+	rs := &ReplicaSet{options: opts, done: make(chan struct{})}
+	for _, db := range dbs {
+		rs.replicas = append(rs.replicas, &replica{db: db})
+	}
+	rs.check()
+	threading.GoSafe(rs.loop)
+	return rs
+}
+
+// NewReplicaSetFromConf 按 conf.Replicas 连接只读副本并创建副本集合，未配置副本时返回 nil
+func NewReplicaSetFromConf(conf configx.DbConf, opts ...configx.Option) (*ReplicaSet, error) {
+	if len(conf.Replicas) == 0 {
+		return nil, nil
+	}
+	dbs, err := configx.GetReplicaConnects(conf, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplicaSet(dbs, ReplicaOptions{
+		Policy:         conf.ReplicaPolicy,
+		ReadYourWrites: time.Duration(conf.ReadYourWrites) * time.Millisecond,
+		CheckInterval:  time.Duration(conf.HealthCheck) * time.Millisecond,
+	}), nil
+}
+
+// WithPrimary 返回读取固定到主库的上下文
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+// WithReadYourWrites 返回记录写入时间的上下文，通过该上下文写入后，
+// 在 ReplicaOptions.ReadYourWrites 时间窗口内的读取固定到主库，通常在请求入口设置
+func WithReadYourWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(writeCtxKey{}).(*writeState); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, writeCtxKey{}, &writeState{})
+}
+
+// markWrite 记录上下文的写入时间
+func markWrite(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	if w, ok := ctx.Value(writeCtxKey{}).(*writeState); ok {
+		w.last.Store(time.Now().UnixNano())
+	}
+}
+
+// Stats 返回各副本的状态，顺序与创建时一致
+func (rs *ReplicaSet) Stats() []ReplicaStat {
+	if rs == nil {
+		return nil
+	}
+	stats := make([]ReplicaStat, len(rs.replicas))
+	for i, r := range rs.replicas {
+		stats[i] = ReplicaStat{Healthy: r.healthy.Load(), Latency: time.Duration(r.latency.Load())}
+	}
+	return stats
+}
+
+// Close 停止健康检查并关闭副本连接
+func (rs *ReplicaSet) Close() error {
+	if rs == nil {
+		return nil
+	}
+	var err error
+	rs.once.Do(func() {
+		close(rs.done)
+		for _, r := range rs.replicas {
+			sqlDB, e := r.db.DB()
+			if e == nil {
+				e = sqlDB.Close()
+			}
+			if e != nil && err == nil {
+				err = e
+			}
+		}
+	})
+	return err
+}
+
+// usePlugins 为副本启用主库已注册、副本尚未注册的插件
+func (rs *ReplicaSet) usePlugins(primary *gorm.DB) {
+	if rs == nil || primary == nil {
+		return
+	}
+	names := make([]string, 0, len(primary.Config.Plugins))
+	for name := range primary.Config.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for _, r := range rs.replicas {
+		for _, name := range names {
+			if _, ok := r.db.Config.Plugins[name]; ok {
+				continue
+			}
+			if err := r.db.Use(primary.Config.Plugins[name]); err != nil {
+				logx.Errorf("只读副本启用插件 %s 出错: %v", name, err)
+			}
+		}
+	}
+}
+
+// pick 选择读连接，上下文固定主库、处于读写一致窗口或没有健康副本时返回 primary
+func (rs *ReplicaSet) pick(ctx context.Context, primary *gorm.DB) *gorm.DB {
+	if rs == nil || len(rs.replicas) == 0 || rs.pinned(ctx) {
+		return primary
+	}
+	var best *replica
+	if rs.options.Policy == ReplicaLeastLatency {
+		for _, r := range rs.replicas {
+			if r.healthy.Load() && (best == nil || r.latency.Load() < best.latency.Load()) {
+				best = r
+			}
+		}
+	} else {
+		n := uint64(len(rs.replicas))
+		start := rs.next.Add(1)
+		for i := uint64(0); i < n; i++ {
+			if r := rs.replicas[(start+i)%n]; r.healthy.Load() {
+				best = r
+				break
+			}
+		}
+	}
+	if best == nil {
+		return primary
+	}
+	return best.db
+}
+
+func (rs *ReplicaSet) pinned(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	if _, ok := ctx.Value(primaryCtxKey{}).(bool); ok {
+		return true
+	}
+	if _, ok := cacheTxFrom(ctx); ok {
+		return true
+	}
+	if w, ok := ctx.Value(writeCtxKey{}).(*writeState); ok {
+		if last := w.last.Load(); last > 0 {
+			return time.Since(time.Unix(0, last)) < rs.options.ReadYourWrites
+		}
+	}
+	return false
+}
+
+func (rs *ReplicaSet) loop() {
+	ticker := time.NewTicker(rs.options.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rs.done:
+			return
+		case <-ticker.C:
+			rs.check()
+		}
+	}
+}
+
+// check 并发探测全部副本，失败的副本移出分发，成功时更新延迟
+func (rs *ReplicaSet) check() {
+	var wg sync.WaitGroup
+	for _, r := range rs.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			r.probe(rs.options.CheckTimeout)
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (r *replica) probe(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	sqlDB, err := r.db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		if r.healthy.Swap(false) {
+			logx.Errorf("只读副本健康检查失败，暂停分发: %v", err)
+		}
+		return
+	}
+	latency := int64(time.Since(start))
+	if old := r.latency.Load(); old > 0 {
+		latency = int64(float64(old)*latencyDecay + float64(latency)*(1-latencyDecay))
+	}
+	r.latency.Store(latency)
+	if !r.healthy.Swap(true) {
+		logx.Infof("只读副本健康检查通过，加入分发")
+	}
+}
@@ -0,0 +1,103 @@
+package gormx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qiaogw/sub-sdk/gormx/plugins"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestReplicaSet(t *testing.T) {
+	ctx := context.Background()
+	primary := newCursorDB(t)
+	replicas := make([]*gorm.DB, 2)
+	for i, name := range []string{"r1", "r2"} {
+		replicas[i] = newCursorDB(t)
+		assert.Nil(t, replicas[i].Model(&searchUser{}).Where("id = ?", 1).Update("name", name).Error)
+	}
+	rs := NewReplicaSet(replicas, ReplicaOptions{ReadYourWrites: 50 * time.Millisecond, CheckInterval: time.Hour})
+	defer rs.Close()
+	conn := NewSqlConn(primary).WithReplicas(rs)
+	read := func(ctx context.Context) string {
+		var u searchUser
+		assert.Nil(t, conn.QueryNoCacheCtx(ctx, func(conn *gorm.DB) error {
+			return conn.First(&u, 1).Error
+		}))
+		return u.Name
+	}
+
+	// 轮询健康副本
+	seen := map[string]bool{read(ctx): true, read(ctx): true}
+	assert.Equal(t, map[string]bool{"r1": true, "r2": true}, seen)
+	assert.Equal(t, "u", read(WithPrimary(ctx)))
+
+	// 写入后的时间窗口内读取主库
+	rywCtx := WithReadYourWrites(ctx)
+	assert.NotEqual(t, "u", read(rywCtx))
+	assert.Nil(t, conn.ExecNoCacheCtx(rywCtx, func(conn *gorm.DB) error {
+		return conn.Model(&searchUser{}).Where("id = ?", 2).Update("status", 9).Error
+	}))
+	assert.Equal(t, "u", read(rywCtx))
+	time.Sleep(60 * time.Millisecond)
+	assert.NotEqual(t, "u", read(rywCtx))
+
+	// 缓存未命中时从副本加载，事务中读取主库
+	cc := NewConnWithCache(primary, newMemCache()).WithReplicas(rs)
+	var u searchUser
+	assert.Nil(t, cc.QueryCtx(ctx, &u, "cache:user:id:1", func(conn *gorm.DB) error {
+		return conn.First(&u, 1).Error
+	}))
+	assert.NotEqual(t, "u", u.Name)
+	assert.Nil(t, cc.TransactCtx(ctx, func(tx *gorm.DB) error {
+		return cc.QueryNoCacheCtx(tx.Statement.Context, func(conn *gorm.DB) error {
+			return conn.First(&u, 1).Error
+		})
+	}))
+	assert.Equal(t, "u", u.Name)
+
+	// 健康检查失败的副本移出分发，全部失败时读取主库
+	sqlDB, _ := replicas[0].DB()
+	assert.Nil(t, sqlDB.Close())
+	rs.check()
+	assert.False(t, rs.Stats()[0].Healthy)
+	assert.True(t, rs.Stats()[1].Healthy)
+	assert.Equal(t, "r2", read(ctx))
+	assert.Equal(t, "r2", read(ctx))
+	sqlDB, _ = replicas[1].DB()
+	assert.Nil(t, sqlDB.Close())
+	rs.check()
+	assert.Equal(t, "u", read(ctx))
+
+	// 最低延迟策略选择延迟最低的健康副本
+	fast, slow := newCursorDB(t), newCursorDB(t)
+	assert.Nil(t, fast.Model(&searchUser{}).Where("id = ?", 1).Update("name", "fast").Error)
+	ll := NewReplicaSet([]*gorm.DB{slow, fast}, ReplicaOptions{Policy: ReplicaLeastLatency, CheckInterval: time.Hour})
+	defer ll.Close()
+	ll.replicas[0].latency.Store(int64(time.Second))
+	ll.replicas[1].latency.Store(int64(time.Millisecond))
+	assert.Equal(t, fast, ll.pick(ctx, primary))
+}
+
+func TestReplicaPlugins(t *testing.T) {
+	assert.Contains(t, plugins.Names(), "datascope")
+	primary, replica := newCursorDB(t), newCursorDB(t)
+	assert.Nil(t, primary.Use(&TenantPlugin{}))
+	assert.Nil(t, replica.AutoMigrate(&tenantOrder{}))
+	assert.Nil(t, replica.Create(&[]tenantOrder{{TenantId: "t1", Title: "a"}, {TenantId: "t2", Title: "b"}}).Error)
+	rs := NewReplicaSet([]*gorm.DB{replica}, ReplicaOptions{CheckInterval: time.Hour})
+	defer rs.Close()
+
+	// 副本启用主库的插件，读取副本时同样按租户过滤
+	conn := NewSqlConn(primary).WithReplicas(rs)
+	var orders []tenantOrder
+	assert.Nil(t, conn.QueryNoCacheCtx(WithTenant(context.Background(), "t1"), func(conn *gorm.DB) error {
+		return conn.Find(&orders).Error
+	}))
+	assert.Len(t, orders, 1)
+	assert.Equal(t, "a", orders[0].Title)
+	_, ok := replica.Config.Plugins[(&TenantPlugin{}).Name()]
+	assert.True(t, ok)
+}
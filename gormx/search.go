@@ -5,11 +5,12 @@ import (
 	"github.com/qiaogw/sub-sdk/gormx/configx"
 	"github.com/qiaogw/sub-sdk/gormx/gen"
 	"gorm.io/gorm"
-	"reflect"
 )
 
 // SearchKey 根据表名和关键字生成SQL查询条件
 // 该函数会根据数据库类型（如MySQL或PostgreSQL）生成不同的SQL查询条件
+//
+// Deprecated: 关键字直接拼接进 SQL，存在注入风险，请使用 KeywordSearch
 func SearchKey(db *gorm.DB, table, key string) string {
 	var sql string
 
@@ -38,39 +39,50 @@ func SearchKey(db *gorm.DB, table, key string) string {
 
 // MakeCondition 根据查询对象和数据库驱动生成GORM查询条件
 // 该函数会解析查询对象并生成对应的GORM查询条件，包括JOIN、WHERE、OR和ORDER BY等
+// 条件按结构体字段声明顺序生成；search 标签错误时通过 db.AddError 返回
+// 方言优先按 db.Dialector 选择，未注册时再按 driver 查找
 func MakeCondition(q interface{}, driver string) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		condition := &GormCondition{
 			GormPublic: GormPublic{},
 			Join:       make([]*GormJoin, 0),
 		}
-		ResolveSearchQuery(driver, q, condition)
-		for _, join := range condition.Join {
-			if join == nil {
-				continue
-			}
-			db = db.Joins(join.JoinOn)
-			for k, v := range join.Where {
-				db = db.Where(k, v...)
-			}
-			for k, v := range join.Or {
-				db = db.Or(k, v...)
-			}
-			for _, o := range join.Order {
-				db = db.Order(o)
-			}
+		name, d := resolveDialect(dialectorName(db), driver)
+		if err := resolveSearchQuery(name, d, q, condition); err != nil {
+			_ = db.AddError(err)
+			return db
 		}
-		for k, v := range condition.Where {
-			db = db.Where(k, v...)
+		return applyCondition(db, condition)
+	}
+}
+
+// applyCondition 将解析好的条件按顺序应用到查询
+func applyCondition(db *gorm.DB, condition *GormCondition) *gorm.DB {
+	for _, join := range condition.Join {
+		if join == nil {
+			continue
 		}
-		for k, v := range condition.Or {
-			db = db.Or(k, v...)
+		db = joinOnce(db, join.JoinOn)
+		for _, w := range join.Where {
+			db = db.Where(w.Query, w.Args...)
 		}
-		for _, o := range condition.Order {
+		for _, o := range join.Or {
+			db = db.Or(o.Query, o.Args...)
+		}
+		for _, o := range join.Order {
 			db = db.Order(o)
 		}
-		return db
 	}
+	for _, w := range condition.Where {
+		db = db.Where(w.Query, w.Args...)
+	}
+	for _, o := range condition.Or {
+		db = db.Or(o.Query, o.Args...)
+	}
+	for _, o := range condition.Order {
+		db = db.Order(o)
+	}
+	return db
 }
 
 // Paginate 根据分页参数生成GORM分页查询条件
@@ -87,6 +99,8 @@ func Paginate(pageSize, pageIndex int64) func(db *gorm.DB) *gorm.DB {
 
 // SortBy 根据排序字段和排序方向生成GORM排序查询条件
 // 该函数会根据传入的排序字段和排序方向（升序或降序）生成对应的排序查询条件
+//
+// Deprecated: sortBy 直接写入 ORDER BY，不能传入前端参数，请使用 OrderBy
 func SortBy(sortBy string, descending bool) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		var orderBy string
@@ -98,30 +112,3 @@ func SortBy(sortBy string, descending bool) func(db *gorm.DB) *gorm.DB {
 		return db.Order(orderBy)
 	}
 }
-
-// GetSortBy 根据传入的结构体和字段名获取对应的排序字段
-// 该函数会从结构体的字段标签中解析出对应的排序字段，并返回该字段的名称
-func GetSortBy(sort interface{}, fieldName string) string {
-	tagJson := "json"
-	rt := ""
-	var t *resolveSearchTag
-	// 获取结构体的类型
-	qType := reflect.TypeOf(sort)
-	// 遍历结构体的字段
-	for i := 0; i < qType.NumField(); i++ {
-		tag, ok := qType.Field(i).Tag.Lookup(FromQueryTag)
-		if !ok {
-			continue
-		}
-		switch tag {
-		case "-":
-			continue
-		}
-		t = makeTag(tag)
-		if qType.Field(i).Tag.Get(tagJson) == fieldName {
-			rt = t.Column
-			break
-		}
-	}
-	return rt
-}
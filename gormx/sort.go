@@ -1,17 +1,335 @@
 package gormx
 
 import (
+	"fmt"
+	"reflect"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/qiaogw/sub-sdk/gormx/modelx"
+	"github.com/qiaogw/sub-sdk/gormx/plugins"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
-// GetMaxSort 获取最大排序
-func GetMaxSort(db *gorm.DB, tableName string) (any, error) {
-	var maxSort any
-	err := db.Table(tableName).Select("COALESCE(MAX(sort), 0)").Scan(&maxSort).Error
+// SortGap 相邻记录 sort 的默认间隔，间隔耗尽时自动重排
+const SortGap int64 = 1024
+
+// ErrSortNotFound 待排序的记录不存在或不在同一分组
+var ErrSortNotFound = errx.NewErrCodeMsg(errx.RequestParamError, "排序记录不存在或不在同一分组")
+
+type (
+	// Sorter 排序服务，维护 modelx.ModelUtils.Sort 等整数排序列，sort 越小越靠前
+	// 相邻记录之间保留间隔，移动时取前后记录的中间值，间隔耗尽时重排所在分组；
+	// 移动在事务中执行并锁定分组内的记录，并发调整同一分组时依次执行
+	Sorter struct {
+		Table       string // 表名
+		GroupColumn string // 分组列，e.g. parent_id；为空时整表为一组
+		SortColumn  string // 排序列，默认 sort
+		IdColumn    string // 主键列，默认 id
+		Gap         int64  // 相邻记录的间隔，默认 SortGap
+	}
+
+	// sortRow 分组内记录的主键及排序值
+	sortRow struct {
+		id   interface{}
+		sort int64
+	}
+
+	// SortPlugin 排序插件，新增实现 modelx.Sortable 的模型（嵌入 modelx.ModelUtils）且 sort 为零值时，
+	// 在 gorm 默认事务中通过 Sorter.NextSort 排在分组末尾，分组列由 modelx.SortGrouper 指定
+	SortPlugin struct{}
+)
+
+func init() {
+	plugins.Register("sort", &SortPlugin{})
+}
+
+// NewSorter 创建排序服务，groupColumn 为空时整表为一组
+func NewSorter(table, groupColumn string) *Sorter {
+	return &Sorter{Table: table, GroupColumn: groupColumn}
+}
+
+// NextSort 新增记录的排序值，排在分组末尾；groupColumn 为空时忽略 group，group 为 nil 时为空分组
+// 先锁定分组内的记录再读取最大值，须在事务中调用并在同一事务中写入记录，并发新增同一分组时依次取值；
+// 分组内还没有记录时无记录可锁
+func (s *Sorter) NextSort(db *gorm.DB, group interface{}) (int64, error) {
+	if _, err := s.groupRows(db, group); err != nil {
+		return 0, err
+	}
+	// 等待锁期间提交的记录不在加锁查询的结果中，重新读取
+	maxSort, err := s.maxSort(db, group)
+	if err != nil {
+		return 0, err
+	}
+	return maxSort + s.gap(), nil
+}
+
+// maxSort 分组内最大的排序值，没有记录时为 0
+func (s *Sorter) maxSort(db *gorm.DB, group interface{}) (int64, error) {
+	var maxSort int64
+	tx := s.whereGroup(db.Table(s.Table), group).
+		Select(fmt.Sprintf("COALESCE(MAX(%s), 0)", s.quote(db, s.sortColumn())))
+	err := tx.Scan(&maxSort).Error
 	return maxSort, err
 }
 
+// MoveBefore 将 id 移动到 otherId 之前，两者须在同一分组
+func (s *Sorter) MoveBefore(db *gorm.DB, id, otherId interface{}) error {
+	return s.move(db, id, func(rows []sortRow) (int, error) {
+		i := sortIndex(rows, otherId)
+		if i < 0 {
+			return 0, ErrSortNotFound
+		}
+		return i, nil
+	})
+}
+
+// MoveAfter 将 id 移动到 otherId 之后，两者须在同一分组
+func (s *Sorter) MoveAfter(db *gorm.DB, id, otherId interface{}) error {
+	return s.move(db, id, func(rows []sortRow) (int, error) {
+		i := sortIndex(rows, otherId)
+		if i < 0 {
+			return 0, ErrSortNotFound
+		}
+		return i + 1, nil
+	})
+}
+
+// MoveToTop 将 id 移动到分组最前
+func (s *Sorter) MoveToTop(db *gorm.DB, id interface{}) error {
+	return s.move(db, id, func([]sortRow) (int, error) {
+		return 0, nil
+	})
+}
+
+// MoveToBottom 将 id 移动到分组最后
+func (s *Sorter) MoveToBottom(db *gorm.DB, id interface{}) error {
+	return s.move(db, id, func(rows []sortRow) (int, error) {
+		return len(rows), nil
+	})
+}
+
+// move 在事务中锁定 id 所在分组，position 返回 id 在分组其余记录中的目标位置
+func (s *Sorter) move(db *gorm.DB, id interface{}, position func(rows []sortRow) (int, error)) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Session(&gorm.Session{NewDB: true, Context: tx.Statement.Context})
+		rows, err := s.lockGroup(tx, id)
+		if err != nil {
+			return err
+		}
+		i := sortIndex(rows, id)
+		if i < 0 {
+			return ErrSortNotFound
+		}
+		current := rows[i]
+		rows = append(rows[:i:i], rows[i+1:]...)
+		pos, err := position(rows)
+		if err != nil {
+			return err
+		}
+		prev, next := int64(0), int64(-1)
+		if pos > 0 {
+			prev = rows[pos-1].sort
+		}
+		if pos < len(rows) {
+			next = rows[pos].sort
+		}
+		switch {
+		case next < 0:
+			return s.update(tx, current, prev+s.gap())
+		case next-prev > 1:
+			return s.update(tx, current, prev+(next-prev)/2)
+		}
+		// 间隔耗尽，按目标顺序重排整个分组
+		rows = append(rows[:pos:pos], append([]sortRow{current}, rows[pos:]...)...)
+		for n, row := range rows {
+			if err = s.update(tx, row, int64(n+1)*s.gap()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lockGroup 锁定并按顺序返回 id 所在分组的全部记录
+func (s *Sorter) lockGroup(tx *gorm.DB, id interface{}) ([]sortRow, error) {
+	var group interface{}
+	if s.GroupColumn != "" {
+		var groups []interface{}
+		err := tx.Table(s.Table).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(s.quote(tx, s.idColumn())+" = ?", id).Limit(1).Pluck(s.GroupColumn, &groups).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(groups) == 0 {
+			return nil, ErrSortNotFound
+		}
+		group = scanValue(groups[0])
+	}
+	return s.groupRows(tx, group)
+}
+
+// groupRows 锁定并按顺序返回分组的全部记录
+func (s *Sorter) groupRows(tx *gorm.DB, group interface{}) ([]sortRow, error) {
+	idCol, sortCol := s.quote(tx, s.idColumn()), s.quote(tx, s.sortColumn())
+	q := s.whereGroup(tx.Table(s.Table), group).Clauses(clause.Locking{Strength: "UPDATE"})
+	rs, err := q.Select(idCol, "COALESCE("+sortCol+", 0)").Order(sortCol).Order(idCol).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	var rows []sortRow
+	for rs.Next() {
+		var row sortRow
+		if err = rs.Scan(&row.id, &row.sort); err != nil {
+			return nil, err
+		}
+		row.id = scanValue(row.id)
+		rows = append(rows, row)
+	}
+	return rows, rs.Err()
+}
+
+// update 更新记录的排序值，未变化时跳过
+func (s *Sorter) update(tx *gorm.DB, row sortRow, sort int64) error {
+	if row.sort == sort {
+		return nil
+	}
+	return tx.Table(s.Table).Where(s.quote(tx, s.idColumn())+" = ?", row.id).
+		Update(s.sortColumn(), sort).Error
+}
+
+// whereGroup 限定分组，groupColumn 为空时不限定
+func (s *Sorter) whereGroup(tx *gorm.DB, group interface{}) *gorm.DB {
+	if s.GroupColumn == "" {
+		return tx
+	}
+	if group == nil {
+		return tx.Where(s.quote(tx, s.GroupColumn) + " IS NULL")
+	}
+	return tx.Where(s.quote(tx, s.GroupColumn)+" = ?", group)
+}
+
+func (s *Sorter) quote(db *gorm.DB, column string) string {
+	return DialectOf(db).Quote(column)
+}
+
+func (s *Sorter) sortColumn() string {
+	if s.SortColumn == "" {
+		return "sort"
+	}
+	return s.SortColumn
+}
+
+func (s *Sorter) idColumn() string {
+	if s.IdColumn == "" {
+		return "id"
+	}
+	return s.IdColumn
+}
+
+func (s *Sorter) gap() int64 {
+	if s.Gap <= 1 {
+		return SortGap
+	}
+	return s.Gap
+}
+
+// sortIndex 按主键查找记录位置，不同驱动返回的主键类型不同，按字符串比较
+func sortIndex(rows []sortRow, id interface{}) int {
+	key := fmt.Sprint(id)
+	for i, row := range rows {
+		if fmt.Sprint(row.id) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *SortPlugin) Name() string {
+	return "gorm-zero-sort-plugin"
+}
+
+func (p *SortPlugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Create().Before("gorm:create").Register("gorm-zero-sort:create", p.create)
+}
+
+var _ gorm.Plugin = &SortPlugin{}
+
+// create 为 sort 为零值的新增记录填充分组末尾的排序值，同一批次同一分组的记录依次递增
+func (p *SortPlugin) create(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil {
+		return
+	}
+	model := reflect.New(stmt.Schema.ModelType).Interface()
+	if _, ok := model.(modelx.Sortable); !ok {
+		return
+	}
+	s := &Sorter{Table: stmt.Table}
+	if g, ok := model.(modelx.SortGrouper); ok {
+		s.GroupColumn = g.SortGroupColumn()
+	}
+	field := stmt.Schema.LookUpField(s.sortColumn())
+	if field == nil {
+		return
+	}
+	if pk := stmt.Schema.PrioritizedPrimaryField; pk != nil {
+		s.IdColumn = pk.DBName
+	}
+	var groupField *schema.Field
+	if s.GroupColumn != "" {
+		if groupField = stmt.Schema.LookUpField(s.GroupColumn); groupField == nil {
+			_ = db.AddError(fmt.Errorf("排序分组列不存在：%s.%s", stmt.Table, s.GroupColumn))
+			return
+		}
+	}
+	tx := db.Session(&gorm.Session{NewDB: true, Context: stmt.Context})
+	last := map[string]int64{}
+	fill := func(rv reflect.Value) {
+		if _, zero := field.ValueOf(stmt.Context, rv); !zero {
+			return
+		}
+		var group interface{}
+		if groupField != nil {
+			group, _ = groupField.ValueOf(stmt.Context, rv)
+			if v := reflect.ValueOf(group); v.Kind() == reflect.Ptr && v.IsNil() {
+				group = nil
+			}
+		}
+		key := fmt.Sprint(group)
+		sort, ok := last[key]
+		if ok {
+			sort += s.gap()
+		} else {
+			var err error
+			if sort, err = s.NextSort(tx, group); err != nil {
+				_ = db.AddError(err)
+				return
+			}
+		}
+		last[key] = sort
+		_ = db.AddError(field.Set(stmt.Context, rv, sort))
+	}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		fill(stmt.ReflectValue)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len() && db.Error == nil; i++ {
+			fill(reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	}
+}
+
+// GetMaxSort 获取整表最大排序，新增记录排在分组末尾请使用 Sorter.NextSort 或 SortPlugin
+func GetMaxSort(db *gorm.DB, tableName string) (any, error) {
+	return NewSorter(tableName, "").maxSort(db, nil)
+}
+
 // CalculateSort 计算新 `sort` 值，确保插入到合适位置
+//
+// Deprecated: 反复插入同一位置时精度逐渐耗尽，且不区分分组、不加锁，请使用 Sorter
 func CalculateSort(tx *gorm.DB, tableName string, id any, sort float64) (float64, error) {
 	if sort <= 0 {
 		// 1. 获取最大 `sort`，用于默认新增排序
@@ -33,23 +351,32 @@ func CalculateSort(tx *gorm.DB, tableName string, id any, sort float64) (float64
 		Where("id <> ?", id).
 		Limit(1).
 		Scan(&existingSort).Error
-	if err != nil || existingSort == 0 {
+	if err != nil {
+		return sort, err
+	}
+	if existingSort == 0 {
 		// 如果 `sort` 没有重复，直接使用
 		return sort, nil
 	}
 
 	// 3. 获取前一个 `sort`（比当前小的最大值）
 	var prevSort, nextSort float64
-	tx.Table(tableName).
-		Select("MAX(sort)").
+	err = tx.Table(tableName).
+		Select("COALESCE(MAX(sort), 0)").
 		Where("sort < ?", sort).
-		Scan(&prevSort)
+		Scan(&prevSort).Error
+	if err != nil {
+		return sort, err
+	}
 
 	// 4. 获取后一个 `sort`（比当前大的最小值）
-	tx.Table(tableName).
-		Select("MIN(sort)").
+	err = tx.Table(tableName).
+		Select("COALESCE(MIN(sort), 0)").
 		Where("sort > ?", sort).
-		Scan(&nextSort)
+		Scan(&nextSort).Error
+	if err != nil {
+		return sort, err
+	}
 
 	// 5. 计算新 `sort`
 	var newSort float64
@@ -0,0 +1,93 @@
+package gormx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/qiaogw/sub-sdk/gormx/modelx"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type sortMenu struct {
+	Id       int64 `gorm:"primaryKey"`
+	ParentId int64
+	Sort     int64
+}
+
+func (sortMenu) TableName() string { return "menu" }
+
+type sortedMenu struct {
+	Id       int64 `gorm:"primaryKey"`
+	ParentId int64
+	modelx.ModelUtils
+}
+
+func (sortedMenu) TableName() string { return "sorted_menu" }
+
+func (sortedMenu) SortGroupColumn() string { return "parent_id" }
+
+func menuOrder(t *testing.T, db *gorm.DB, parentId int64) map[int64]int64 {
+	var menus []sortMenu
+	assert.Nil(t, db.Where("parent_id = ?", parentId).Find(&menus).Error)
+	sorts := make(map[int64]int64, len(menus))
+	for _, m := range menus {
+		sorts[m.Id] = m.Sort
+	}
+	return sorts
+}
+
+func TestSorter(t *testing.T) {
+	db := newCursorDB(t)
+	assert.Nil(t, db.AutoMigrate(&sortMenu{}))
+	s := NewSorter("menu", "parent_id")
+
+	for _, m := range []sortMenu{{Id: 1, ParentId: 1}, {Id: 2, ParentId: 1}, {Id: 3, ParentId: 1}, {Id: 4, ParentId: 2}} {
+		sort, err := s.NextSort(db, m.ParentId)
+		assert.Nil(t, err)
+		m.Sort = sort
+		assert.Nil(t, db.Create(&m).Error)
+	}
+	assert.Equal(t, map[int64]int64{1: 1024, 2: 2048, 3: 3072}, menuOrder(t, db, 1))
+
+	assert.Nil(t, s.MoveBefore(db, 3, 1))
+	assert.Nil(t, s.MoveAfter(db, 1, 2))
+	assert.Equal(t, map[int64]int64{3: 512, 2: 2048, 1: 3072}, menuOrder(t, db, 1))
+	assert.Nil(t, s.MoveToTop(db, 1))
+	assert.Nil(t, s.MoveToBottom(db, 3))
+	assert.Equal(t, map[int64]int64{1: 256, 2: 2048, 3: 3072}, menuOrder(t, db, 1))
+
+	// 间隔耗尽时重排分组
+	assert.Nil(t, db.Exec("UPDATE menu SET sort = id WHERE parent_id = 1").Error)
+	assert.Nil(t, s.MoveBefore(db, 3, 2))
+	assert.Equal(t, map[int64]int64{1: 1024, 3: 2048, 2: 3072}, menuOrder(t, db, 1))
+
+	err := s.MoveBefore(db, 4, 1)
+	assert.True(t, errors.Is(err, ErrSortNotFound))
+	err = s.MoveToTop(db, 9)
+	assert.True(t, errors.Is(err, ErrSortNotFound))
+}
+
+func TestSortPlugin(t *testing.T) {
+	db := newCursorDB(t)
+	assert.Nil(t, db.Use(&SortPlugin{}))
+	assert.Nil(t, db.AutoMigrate(&sortedMenu{}))
+
+	// 同一批次同一分组依次递增，已指定的排序值不变
+	assert.Nil(t, db.Create(&[]sortedMenu{{Id: 1, ParentId: 1}, {Id: 2, ParentId: 2}, {Id: 3, ParentId: 1},
+		{Id: 4, ParentId: 1, ModelUtils: modelx.ModelUtils{Sort: 10}}}).Error)
+	menu := sortedMenu{Id: 5, ParentId: 1}
+	assert.Nil(t, db.Create(&menu).Error)
+	assert.Equal(t, int64(3072), menu.Sort)
+	var menus []sortedMenu
+	assert.Nil(t, db.Order("id").Find(&menus).Error)
+	sorts := make([]int64, 0, len(menus))
+	for _, m := range menus {
+		sorts = append(sorts, m.Sort)
+	}
+	assert.Equal(t, []int64{1024, 1024, 2048, 10, 3072}, sorts)
+
+	maxSort, err := GetMaxSort(db, "sorted_menu")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3072), maxSort)
+}
@@ -0,0 +1,223 @@
+package gormx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/qiaogw/sub-sdk/gormx/plugins"
+	"github.com/qiaogw/sub-sdk/jwtx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultTenantColumn 默认的租户列
+	DefaultTenantColumn = "tenant_id"
+
+	tenantCallbackName = "gorm-zero-tenant"
+)
+
+// ErrTenantRequired 上下文中没有租户
+var ErrTenantRequired = errx.NewErrCodeMsg(errx.ErrAuth, "缺少租户信息")
+
+type (
+	// TenantPlugin 多租户插件（按列隔离），从 context.Context 读取租户 id，
+	// 对含租户列的模型在查询、更新、删除时追加租户条件，新增时写入租户 id；
+	// 上下文中没有租户时返回 ErrTenantRequired，跨租户访问需通过 SkipTenant 显式跳过；Raw、Exec 不受影响
+	TenantPlugin struct {
+		Column string // 租户列，为空时使用 tenant_id
+	}
+
+	tenantKey     struct{}
+	skipTenantKey struct{}
+)
+
+func init() {
+	plugins.Register("tenant", &TenantPlugin{})
+}
+
+// WithTenant 将租户 id 写入上下文
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext 从上下文读取租户 id，WithTenant 写入的优先，其次为 jwt 中的 tenantId，
+// 最后为 middlewarex.TenantMiddleware 从请求头读取的租户 id
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+		return tenant, true
+	}
+	if tenant := jwtx.GetTenantIdFromCtx(ctx); tenant != "" {
+		return tenant, true
+	}
+	tenant := jwtx.GetHeaderTenantIdFromCtx(ctx)
+	return tenant, tenant != ""
+}
+
+// SkipTenant 跳过租户隔离，用于跨租户的管理、统计等场景
+func SkipTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipTenantKey{}, true)
+}
+
+func isSkipTenant(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	skip, _ := ctx.Value(skipTenantKey{}).(bool)
+	return skip
+}
+
+func (p *TenantPlugin) Name() string {
+	return "gorm-zero-tenant-plugin"
+}
+
+func (p *TenantPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register(tenantCallbackName+":create", p.create); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register(tenantCallbackName+":query", p.where); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register(tenantCallbackName+":row", p.where); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register(tenantCallbackName+":update", p.update); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register(tenantCallbackName+":delete", p.where)
+}
+
+var _ gorm.Plugin = &TenantPlugin{}
+
+// where 追加租户条件
+func (p *TenantPlugin) where(db *gorm.DB) {
+	field, tenant, ok := p.prepare(db)
+	if !ok {
+		return
+	}
+	addGroupedWhere(db.Statement, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenant})
+}
+
+// update 追加租户条件并忽略对租户列的赋值，将租户列改为其他租户时返回 errx.ErrAuth
+func (p *TenantPlugin) update(db *gorm.DB) {
+	field, tenant, ok := p.prepare(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	if v, ok := tenantAssignment(stmt, field); ok && fmt.Sprint(v) != tenant {
+		_ = db.AddError(errx.NewErrCode(errx.ErrAuth))
+		return
+	}
+	stmt.Omits = append(stmt.Omits, field.DBName)
+	addGroupedWhere(stmt, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenant})
+}
+
+// create 写入租户 id，已指定其他租户时返回 errx.ErrAuth
+func (p *TenantPlugin) create(db *gorm.DB) {
+	field, tenant, ok := p.prepare(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	stamp := func(rv reflect.Value) {
+		v, zero := field.ValueOf(stmt.Context, rv)
+		if zero {
+			_ = db.AddError(field.Set(stmt.Context, rv, tenant))
+		} else if fmt.Sprint(v) != tenant {
+			_ = db.AddError(errx.NewErrCode(errx.ErrAuth))
+		}
+	}
+	stampMap := func(m map[string]interface{}) {
+		for k, v := range m {
+			if f := stmt.Schema.LookUpField(k); f == field {
+				if fmt.Sprint(v) != tenant {
+					_ = db.AddError(errx.NewErrCode(errx.ErrAuth))
+				}
+				return
+			}
+		}
+		m[field.DBName] = tenant
+	}
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		stampMap(dest)
+		return
+	case *map[string]interface{}:
+		stampMap(*dest)
+		return
+	case []map[string]interface{}:
+		for i := 0; i < len(dest) && db.Error == nil; i++ {
+			stampMap(dest[i])
+		}
+		return
+	case *[]map[string]interface{}:
+		for i := 0; i < len(*dest) && db.Error == nil; i++ {
+			stampMap((*dest)[i])
+		}
+		return
+	}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		stamp(stmt.ReflectValue)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len() && db.Error == nil; i++ {
+			stamp(reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	}
+}
+
+// tenantAssignment 更新语句中对租户列的赋值，结构体中租户列为零值时视为未赋值
+func tenantAssignment(stmt *gorm.Statement, field *schema.Field) (interface{}, bool) {
+	var m map[string]interface{}
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		m = dest
+	case *map[string]interface{}:
+		m = *dest
+	default:
+		rv := reflect.Indirect(reflect.ValueOf(stmt.Dest))
+		if rv.Kind() != reflect.Struct || rv.Type() != stmt.Schema.ModelType {
+			return nil, false
+		}
+		v, zero := field.ValueOf(stmt.Context, rv)
+		return v, !zero
+	}
+	for k, v := range m {
+		if stmt.Schema.LookUpField(k) == field {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// prepare 返回当前语句的租户列及租户 id，ok 为 false 时不处理或已出错
+func (p *TenantPlugin) prepare(db *gorm.DB) (*schema.Field, string, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || isSkipTenant(stmt.Context) {
+		return nil, "", false
+	}
+	field := stmt.Schema.LookUpField(p.column())
+	if field == nil || field.DBName == "" {
+		return nil, "", false
+	}
+	tenant, ok := TenantFromContext(stmt.Context)
+	if !ok {
+		_ = db.AddError(ErrTenantRequired)
+		return nil, "", false
+	}
+	return field, tenant, true
+}
+
+func (p *TenantPlugin) column() string {
+	if p.Column != "" {
+		return p.Column
+	}
+	return DefaultTenantColumn
+}
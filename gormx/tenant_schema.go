@@ -0,0 +1,92 @@
+package gormx
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/qiaogw/sub-sdk/errx"
+	"github.com/qiaogw/sub-sdk/gormx/configx"
+	"gorm.io/gorm"
+)
+
+// tenantSchemaPattern 租户 schema 名只允许字母、数字、下划线，不超过 Postgres 标识符长度
+var tenantSchemaPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,63}$`)
+
+// tenantSchemaIdleTTL 租户连接池空闲多久后关闭
+const tenantSchemaIdleTTL = 10 * time.Minute
+
+// TenantSchemas 多租户（按 schema 隔离），仅支持 Postgres
+// 每个租户使用 search_path 为 Prefix+租户 id 的独立连接池，首次使用时创建，连接池参数同 DbConf，
+// 空闲 10 分钟后关闭；租户较多时请调小 DbConf.MaxOpenConns、MaxIdleConns
+type TenantSchemas struct {
+	conf    configx.DbConf
+	prefix  string
+	sources *configx.DataSourceManager // 名称为 schema 名，空字符串为 conf.Schema
+}
+
+// NewTenantSchemas 创建按 schema 隔离的租户连接，prefix 为租户 schema 的前缀，e.g. "tenant_"
+func NewTenantSchemas(conf configx.DbConf, prefix string, opts ...configx.Option) (*TenantSchemas, error) {
+	if conf.Driver != string(configx.Postgres) {
+		return nil, fmt.Errorf("按 schema 隔离租户只支持 %s，不支持：%s", configx.Postgres, conf.Driver)
+	}
+	return &TenantSchemas{conf: conf, prefix: prefix, sources: configx.NewDataSourceManager(tenantSchemaIdleTTL, opts...)}, nil
+}
+
+// DB 返回上下文中租户的连接；SkipTenant 的上下文返回 conf.Schema 的连接，没有租户时返回 ErrTenantRequired
+func (s *TenantSchemas) DB(ctx context.Context) (*gorm.DB, error) {
+	name := ""
+	if !isSkipTenant(ctx) {
+		tenant, ok := TenantFromContext(ctx)
+		if !ok {
+			return nil, ErrTenantRequired
+		}
+		var err error
+		if name, err = s.Schema(tenant); err != nil {
+			return nil, err
+		}
+	}
+	db, err := s.open(name)
+	if err != nil {
+		return nil, err
+	}
+	return db.WithContext(ctx), nil
+}
+
+// Schema 返回租户的 schema 名
+func (s *TenantSchemas) Schema(tenant string) (string, error) {
+	name := s.prefix + tenant
+	if !tenantSchemaPattern.MatchString(name) {
+		return "", errx.NewErrorf(errx.RequestParamError, "租户 schema 名无效：%s", name)
+	}
+	return name, nil
+}
+
+// CreateSchema 创建租户的 schema，已存在时忽略
+func (s *TenantSchemas) CreateSchema(ctx context.Context, tenant string) error {
+	name, err := s.Schema(tenant)
+	if err != nil {
+		return err
+	}
+	db, err := s.open("")
+	if err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Exec(fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS "%s"`, name)).Error
+}
+
+// Close 关闭全部连接池
+func (s *TenantSchemas) Close() error {
+	s.sources.Close()
+	return nil
+}
+
+// open 返回 schema 的连接池，不存在时创建；各 schema 分别连接，互不阻塞
+func (s *TenantSchemas) open(name string) (*gorm.DB, error) {
+	conf := s.conf
+	if name != "" {
+		conf.Schema = name
+	}
+	return s.sources.Open(name, conf)
+}
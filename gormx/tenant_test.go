@@ -0,0 +1,109 @@
+package gormx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qiaogw/sub-sdk/gormx/configx"
+	"github.com/qiaogw/sub-sdk/jwtx"
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantOrder struct {
+	Id       int64
+	TenantId string
+	Title    string
+}
+
+func TestTenantPlugin(t *testing.T) {
+	db := newCursorDB(t)
+	assert.Nil(t, db.Use(&TenantPlugin{}))
+	assert.Nil(t, db.AutoMigrate(&tenantOrder{}))
+	t1 := WithTenant(context.Background(), "t1")
+	t2 := context.WithValue(context.Background(), jwtx.CtxKeyJwtTenantId, "t2")
+
+	// 新增时写入租户，指定其他租户时拒绝
+	assert.Nil(t, db.WithContext(t1).Create(&[]tenantOrder{{Title: "a"}, {Title: "b"}}).Error)
+	assert.Nil(t, db.WithContext(t2).Create(&tenantOrder{Title: "c"}).Error)
+	assert.NotNil(t, db.WithContext(t1).Create(&tenantOrder{TenantId: "t2", Title: "d"}).Error)
+
+	var orders []tenantOrder
+	assert.Nil(t, db.WithContext(t1).Order("id").Find(&orders).Error)
+	assert.Equal(t, []string{"a", "b"}, []string{orders[0].Title, orders[1].Title})
+	var count int64
+	assert.Nil(t, db.WithContext(t2).Model(&tenantOrder{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// 更新、删除只影响本租户
+	assert.Nil(t, db.WithContext(t2).Model(&tenantOrder{}).Where("1 = 1").Update("title", "x").Error)
+	assert.Nil(t, db.WithContext(t2).Where("1 = 1").Delete(&tenantOrder{}).Error)
+	assert.Nil(t, db.WithContext(SkipTenant(context.Background())).Order("id").Find(&orders).Error)
+	assert.Len(t, orders, 2)
+	assert.Equal(t, "t1", orders[0].TenantId)
+
+	// 没有租户时拒绝访问，不含租户列的模型不受影响
+	assert.Equal(t, ErrTenantRequired, db.Find(&orders).Error)
+	assert.Nil(t, db.Find(&[]searchUser{}).Error)
+}
+
+func TestTenantPluginIsolation(t *testing.T) {
+	db := newCursorDB(t)
+	assert.Nil(t, db.Use(&TenantPlugin{}))
+	assert.Nil(t, db.AutoMigrate(&tenantOrder{}))
+	t1 := WithTenant(context.Background(), "t1")
+	t2 := WithTenant(context.Background(), "t2")
+	assert.Nil(t, db.WithContext(t1).Create(&tenantOrder{Title: "a"}).Error)
+	assert.Nil(t, db.WithContext(t2).Create(&tenantOrder{Title: "secret"}).Error)
+
+	// 已有的 OR 条件整体加括号，不能越过租户条件
+	var orders []tenantOrder
+	assert.Nil(t, db.WithContext(t1).Where("title = ?", "secret").Or("title = ?", "a").Find(&orders).Error)
+	assert.Len(t, orders, 1)
+	assert.Equal(t, "a", orders[0].Title)
+
+	// map 新增同样写入租户
+	assert.Nil(t, db.WithContext(t1).Model(&tenantOrder{}).Create(map[string]interface{}{"title": "m"}).Error)
+	assert.Nil(t, db.WithContext(t1).Model(&tenantOrder{}).Create(map[string]interface{}{"Title": "n"}).Error)
+	assert.NotNil(t, db.WithContext(t1).Model(&tenantOrder{}).Create(map[string]interface{}{"tenant_id": "t2", "title": "o"}).Error)
+	var count int64
+	assert.Nil(t, db.WithContext(t1).Model(&tenantOrder{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+
+	// 不能把数据改到其他租户，Save 中租户列为零值时不清空
+	assert.NotNil(t, db.WithContext(t1).Model(&tenantOrder{}).Where("1 = 1").Update("tenant_id", "t2").Error)
+	assert.NotNil(t, db.WithContext(t1).Model(&tenantOrder{}).Where("1 = 1").Updates(&tenantOrder{TenantId: "t2"}).Error)
+	assert.Nil(t, db.WithContext(t1).Model(&tenantOrder{}).Where("title = ?", "a").Updates(map[string]interface{}{"tenant_id": "t1", "title": "b"}).Error)
+	assert.Nil(t, db.WithContext(t1).Save(&tenantOrder{Id: orders[0].Id, Title: "c"}).Error)
+	assert.Nil(t, db.WithContext(t1).Model(&tenantOrder{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+	assert.Nil(t, db.WithContext(t2).Model(&tenantOrder{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTenantSchemas(t *testing.T) {
+	_, err := NewTenantSchemas(configx.DbConf{Driver: "sqlite"}, "tenant_")
+	assert.NotNil(t, err)
+	s, err := NewTenantSchemas(configx.DbConf{Driver: "postgres", Dbname: "app"}, "tenant_")
+	assert.Nil(t, err)
+	name, err := s.Schema("t1")
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant_t1", name)
+	_, err = s.DB(WithTenant(context.Background(), `t1"; DROP`))
+	assert.NotNil(t, err)
+	_, err = s.DB(context.Background())
+	assert.Equal(t, ErrTenantRequired, err)
+
+	// 租户连接池由 DataSourceManager 管理，按 schema 复用
+	s = &TenantSchemas{conf: configx.DbConf{Driver: "sqlite", Host: t.TempDir(), Dbname: "app", LogMode: "silent"},
+		sources: configx.NewDataSourceManager(time.Minute)}
+	defer s.Close()
+	db, err := s.open("tenant_t1")
+	assert.Nil(t, err)
+	again, err := s.open("tenant_t1")
+	assert.Nil(t, err)
+	assert.Same(t, db, again)
+	_, err = s.open("")
+	assert.Nil(t, err)
+	assert.Len(t, s.sources.Stats(), 2)
+}
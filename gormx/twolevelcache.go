@@ -0,0 +1,437 @@
+package gormx
+
+import (
+	"container/list"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	red "github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/stringx"
+)
+
+const (
+	defaultL1Expiry     = time.Minute
+	defaultL1MaxEntries = 10000
+	defaultL1MaxBytes   = 64 << 20
+)
+
+type (
+	// L1Conf 进程内缓存配置
+	L1Conf struct {
+		Expiry     time.Duration `json:",default=1m"`    // 过期时间，跨实例失效消息丢失时以此为上限
+		MaxEntries int           `json:",default=10000"` // 最大条目数
+		MaxBytes   int64         `json:",default=67108864"`
+	}
+
+	// CacheBus 跨实例广播缓存失效消息
+	CacheBus interface {
+		Publish(ctx context.Context, msg string) error
+		// Subscribe 订阅失效消息，返回的 stop 用于取消订阅
+		Subscribe(handler func(msg string)) (stop func(), err error)
+	}
+
+	// TwoLevelCache 两级缓存，进程内 LRU 在前，go-zero cache.Cache 在后
+	// 本实例写入、删除缓存时通过 CacheBus 通知其他实例清除进程内缓存，
+	// e.g. NewConnWithCache(db, NewTwoLevelCache(cache.New(c, ...), L1Conf{}, bus))
+	TwoLevelCache struct {
+		l1     *lruCache
+		l2     cache.Cache
+		bus    CacheBus
+		id     string
+		stop   func()
+		l1Stat layerCounter
+		l2Stat layerCounter
+	}
+
+	// layerCounter 单层缓存的累计命中计数，不随时间清零
+	layerCounter struct {
+		total atomic.Uint64
+		hit   atomic.Uint64
+		miss  atomic.Uint64
+	}
+
+	// CacheLayerStat 单层缓存的命中统计
+	CacheLayerStat struct {
+		Total uint64
+		Hit   uint64
+		Miss  uint64
+	}
+
+	// TwoLevelStats 两级缓存的命中统计，L2 未命中即回源数据库
+	TwoLevelStats struct {
+		L1        CacheLayerStat
+		L2        CacheLayerStat
+		L1Entries int
+		L1Bytes   int64
+	}
+
+	// invalidation 失效消息，from 为发送实例，收到自身消息时忽略；
+	// at 为发送实例完成 L2 写入的时间（UnixNano），只清除在此之前开始读取的进程内缓存，迟到的旧消息不清除已刷新的条目，
+	// 实例间的时钟偏差同样由 L1Conf.Expiry 兜底
+	invalidation struct {
+		From string   `json:"from"`
+		Keys []string `json:"keys"`
+		At   int64    `json:"at"`
+	}
+
+	// RedisCacheBus 基于 Redis 发布订阅的 CacheBus
+	RedisCacheBus struct {
+		client  red.UniversalClient
+		channel string
+	}
+
+	lruCache struct {
+		mu         sync.Mutex
+		ll         *list.List
+		items      map[string]*list.Element
+		bytes      int64
+		expiry     time.Duration
+		maxEntries int
+		maxBytes   int64
+	}
+
+	// lruEntry 进程内缓存条目，loadedAt 为开始从 L2 读取或写入 L2 的时间
+	lruEntry struct {
+		key      string
+		data     []byte
+		loadedAt time.Time
+		expireAt time.Time
+	}
+)
+
+// NewTwoLevelCache 创建两级缓存，bus 为空时仅清除本实例的进程内缓存
+func NewTwoLevelCache(l2 cache.Cache, conf L1Conf, bus CacheBus) *TwoLevelCache {
+	c := &TwoLevelCache{
+		l1:  newLruCache(conf),
+		l2:  l2,
+		bus: bus,
+		id:  stringx.Randn(16),
+	}
+	if bus != nil {
+		stop, err := bus.Subscribe(c.onInvalidate)
+		if err != nil {
+			logx.Errorf("订阅缓存失效消息出错: %v", err)
+		} else {
+			c.stop = stop
+		}
+	}
+	return c
+}
+
+// Close 取消订阅失效消息
+func (c *TwoLevelCache) Close() {
+	if c.stop != nil {
+		c.stop()
+	}
+}
+
+// Stats 两级缓存自创建以来的累计命中统计
+func (c *TwoLevelCache) Stats() TwoLevelStats {
+	entries, bytes := c.l1.size()
+	return TwoLevelStats{
+		L1:        c.l1Stat.stat(),
+		L2:        c.l2Stat.stat(),
+		L1Entries: entries,
+		L1Bytes:   bytes,
+	}
+}
+
+func (c *TwoLevelCache) Del(keys ...string) error {
+	return c.DelCtx(context.Background(), keys...)
+}
+
+func (c *TwoLevelCache) DelCtx(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	c.l1.del(keys...)
+	err := c.l2.DelCtx(ctx, keys...)
+	c.publish(ctx, keys)
+	return err
+}
+
+func (c *TwoLevelCache) Get(key string, val any) error {
+	return c.GetCtx(context.Background(), key, val)
+}
+
+func (c *TwoLevelCache) GetCtx(ctx context.Context, key string, val any) error {
+	if c.getL1(key, val) {
+		return nil
+	}
+	c.l2Stat.total.Add(1)
+	start := time.Now()
+	if err := c.l2.GetCtx(ctx, key, val); err != nil {
+		c.l2Stat.miss.Add(1)
+		return err
+	}
+	c.l2Stat.hit.Add(1)
+	c.setL1(key, val, start)
+	return nil
+}
+
+func (c *TwoLevelCache) IsNotFound(err error) bool {
+	return c.l2.IsNotFound(err)
+}
+
+func (c *TwoLevelCache) Set(key string, val any) error {
+	return c.SetCtx(context.Background(), key, val)
+}
+
+func (c *TwoLevelCache) SetCtx(ctx context.Context, key string, val any) error {
+	start := time.Now()
+	if err := c.l2.SetCtx(ctx, key, val); err != nil {
+		return err
+	}
+	c.setL1(key, val, start)
+	c.publish(ctx, []string{key})
+	return nil
+}
+
+func (c *TwoLevelCache) SetWithExpire(key string, val any, expire time.Duration) error {
+	return c.SetWithExpireCtx(context.Background(), key, val, expire)
+}
+
+func (c *TwoLevelCache) SetWithExpireCtx(ctx context.Context, key string, val any, expire time.Duration) error {
+	start := time.Now()
+	if err := c.l2.SetWithExpireCtx(ctx, key, val, expire); err != nil {
+		return err
+	}
+	c.setL1(key, val, start)
+	c.publish(ctx, []string{key})
+	return nil
+}
+
+func (c *TwoLevelCache) Take(val any, key string, query func(val any) error) error {
+	return c.TakeCtx(context.Background(), val, key, query)
+}
+
+func (c *TwoLevelCache) TakeCtx(ctx context.Context, val any, key string, query func(val any) error) error {
+	return c.TakeWithExpireCtx(ctx, val, key, func(val any, _ time.Duration) error {
+		return query(val)
+	})
+}
+
+func (c *TwoLevelCache) TakeWithExpire(val any, key string, query func(val any, expire time.Duration) error) error {
+	return c.TakeWithExpireCtx(context.Background(), val, key, query)
+}
+
+func (c *TwoLevelCache) TakeWithExpireCtx(ctx context.Context, val any, key string,
+	query func(val any, expire time.Duration) error) error {
+	if c.getL1(key, val) {
+		return nil
+	}
+	c.l2Stat.total.Add(1)
+	start := time.Now()
+	var queried bool
+	err := c.l2.TakeWithExpireCtx(ctx, val, key, func(val any, expire time.Duration) error {
+		queried = true
+		return query(val, expire)
+	})
+	if queried {
+		c.l2Stat.miss.Add(1)
+	} else {
+		c.l2Stat.hit.Add(1)
+	}
+	if err != nil {
+		return err
+	}
+	c.setL1(key, val, start)
+	return nil
+}
+
+func (c *TwoLevelCache) getL1(key string, val any) bool {
+	c.l1Stat.total.Add(1)
+	data, ok := c.l1.get(key)
+	if ok && json.Unmarshal(data, val) == nil {
+		c.l1Stat.hit.Add(1)
+		return true
+	}
+	c.l1Stat.miss.Add(1)
+	return false
+}
+
+func (c *TwoLevelCache) setL1(key string, val any, loadedAt time.Time) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return
+	}
+	c.l1.set(key, data, loadedAt)
+}
+
+// publish 通知其他实例清除进程内缓存，失败时仅记录日志，由 L1Conf.Expiry 兜底
+func (c *TwoLevelCache) publish(ctx context.Context, keys []string) {
+	if c.bus == nil {
+		return
+	}
+	msg, err := json.Marshal(invalidation{From: c.id, Keys: keys, At: time.Now().UnixNano()})
+	if err == nil {
+		err = c.bus.Publish(ctx, string(msg))
+	}
+	if err != nil {
+		logx.WithContext(ctx).Errorf("广播缓存失效 %v 出错: %v", keys, err)
+	}
+}
+
+func (c *TwoLevelCache) onInvalidate(msg string) {
+	var inv invalidation
+	if err := json.Unmarshal([]byte(msg), &inv); err != nil {
+		logx.Errorf("缓存失效消息格式错误: %s", msg)
+		return
+	}
+	if inv.From == c.id {
+		return
+	}
+	if inv.At == 0 {
+		c.l1.del(inv.Keys...)
+		return
+	}
+	c.l1.delBefore(time.Unix(0, inv.At), inv.Keys...)
+}
+
+func (s *layerCounter) stat() CacheLayerStat {
+	return CacheLayerStat{Total: s.total.Load(), Hit: s.hit.Load(), Miss: s.miss.Load()}
+}
+
+// NewRedisCacheBus 创建基于 Redis 发布订阅的 CacheBus，同一业务的实例须使用相同的 channel
+func NewRedisCacheBus(conf redis.RedisConf, channel string) *RedisCacheBus {
+	var tlsConfig *tls.Config
+	if conf.Tls {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	var client red.UniversalClient
+	if conf.Type == redis.ClusterType {
+		client = red.NewClusterClient(&red.ClusterOptions{
+			Addrs:     strings.Split(conf.Host, ","),
+			Username:  conf.User,
+			Password:  conf.Pass,
+			TLSConfig: tlsConfig,
+		})
+	} else {
+		client = red.NewClient(&red.Options{
+			Addr:      conf.Host,
+			Username:  conf.User,
+			Password:  conf.Pass,
+			TLSConfig: tlsConfig,
+		})
+	}
+	return &RedisCacheBus{client: client, channel: channel}
+}
+
+func (b *RedisCacheBus) Publish(ctx context.Context, msg string) error {
+	return b.client.Publish(ctx, b.channel, msg).Err()
+}
+
+func (b *RedisCacheBus) Subscribe(handler func(msg string)) (func(), error) {
+	ps := b.client.Subscribe(context.Background(), b.channel)
+	if _, err := ps.Receive(context.Background()); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+	ch := ps.Channel()
+	go func() {
+		for m := range ch {
+			handler(m.Payload)
+		}
+	}()
+	return func() { _ = ps.Close() }, nil
+}
+
+// Close 关闭 Redis 连接
+func (b *RedisCacheBus) Close() error {
+	return b.client.Close()
+}
+
+func newLruCache(conf L1Conf) *lruCache {
+	c := &lruCache{
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		expiry:     conf.Expiry,
+		maxEntries: conf.MaxEntries,
+		maxBytes:   conf.MaxBytes,
+	}
+	if c.expiry <= 0 {
+		c.expiry = defaultL1Expiry
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = defaultL1MaxEntries
+	}
+	if c.maxBytes <= 0 {
+		c.maxBytes = defaultL1MaxBytes
+	}
+	return c
+}
+
+func (c *lruCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expireAt) {
+		c.remove(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e.data, true
+}
+
+// set 写入缓存，超过条目数或字节数上限时淘汰最久未使用的条目，单个值超过字节上限时不缓存
+func (c *lruCache) set(key string, data []byte, loadedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	size := int64(len(key) + len(data))
+	if size > c.maxBytes {
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, data: data, loadedAt: loadedAt, expireAt: time.Now().Add(c.expiry)})
+	c.bytes += size
+	for c.ll.Len() > c.maxEntries || c.bytes > c.maxBytes {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *lruCache) del(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+// delBefore 删除 at 及之前开始加载的条目，之后加载的条目已包含对应的写入
+func (c *lruCache) delBefore(at time.Time, keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok && !el.Value.(*lruEntry).loadedAt.After(at) {
+			c.remove(el)
+		}
+	}
+}
+
+func (c *lruCache) size() (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len(), c.bytes
+}
+
+func (c *lruCache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*lruEntry)
+	delete(c.items, e.key)
+	c.bytes -= int64(len(e.key) + len(e.data))
+}
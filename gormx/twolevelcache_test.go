@@ -0,0 +1,176 @@
+package gormx
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+// memBus 测试用的进程内 CacheBus，替代 Redis 发布订阅
+type memBus struct {
+	mu       sync.Mutex
+	handlers map[int]func(string)
+	next     int
+}
+
+func (b *memBus) Publish(_ context.Context, msg string) error {
+	b.mu.Lock()
+	handlers := make([]func(string), 0, len(b.handlers))
+	for _, h := range b.handlers {
+		handlers = append(handlers, h)
+	}
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h(msg)
+	}
+	return nil
+}
+
+func (b *memBus) Subscribe(handler func(string)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.handlers == nil {
+		b.handlers = map[int]func(string){}
+	}
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}, nil
+}
+
+func TestTwoLevelCache(t *testing.T) {
+	l2, bus := newMemCache(), &memBus{}
+	a := NewTwoLevelCache(l2, L1Conf{MaxEntries: 2}, bus)
+	b := NewTwoLevelCache(l2, L1Conf{}, bus)
+	defer a.Close()
+	defer b.Close()
+
+	var v string
+	assert.Nil(t, a.TakeCtx(context.Background(), &v, "k1", func(val any) error {
+		*val.(*string) = "v1"
+		return nil
+	}))
+	assert.Equal(t, TwoLevelStats{
+		L1: CacheLayerStat{Total: 1, Miss: 1}, L2: CacheLayerStat{Total: 1, Miss: 1}, L1Entries: 1, L1Bytes: 6,
+	}, a.Stats())
+
+	// 进程内缓存命中时不访问 L2
+	assert.Nil(t, l2.Set("k1", "stale"))
+	assert.Nil(t, a.Get("k1", &v))
+	assert.Equal(t, "v1", v)
+	assert.Equal(t, uint64(1), a.Stats().L1.Hit)
+
+	// 其他实例更新后本实例的进程内缓存失效
+	assert.Nil(t, b.Set("k1", "v2"))
+	assert.Nil(t, a.Get("k1", &v))
+	assert.Equal(t, "v2", v)
+	assert.Equal(t, uint64(1), a.Stats().L2.Hit)
+	assert.Nil(t, b.Del("k1"))
+	assert.True(t, a.IsNotFound(a.Get("k1", &v)))
+
+	// 超过条目数上限时淘汰最久未使用的条目
+	for _, key := range []string{"k1", "k2", "k3"} {
+		assert.Nil(t, a.Set(key, key))
+	}
+	assert.Equal(t, 2, a.Stats().L1Entries)
+	_, ok := a.l1.get("k1")
+	assert.False(t, ok)
+
+	// 超过字节数上限、过期的条目不保留
+	l1 := newLruCache(L1Conf{MaxBytes: 8, Expiry: 10 * time.Millisecond})
+	now := time.Now()
+	l1.set("a", []byte("1234"), now)
+	l1.set("b", []byte("1234"), now)
+	_, ok = l1.get("a")
+	assert.False(t, ok)
+	l1.set("c", []byte("123456789"), now)
+	_, ok = l1.get("c")
+	assert.False(t, ok)
+	time.Sleep(20 * time.Millisecond)
+	_, ok = l1.get("b")
+	assert.False(t, ok)
+	entries, bytes := l1.size()
+	assert.Equal(t, 0, entries)
+	assert.Equal(t, int64(0), bytes)
+}
+
+// notifyBus 收到消息并处理后通知测试，用于等待异步的失效消息
+type notifyBus struct {
+	CacheBus
+	got chan string
+}
+
+func (b *notifyBus) Subscribe(handler func(string)) (func(), error) {
+	return b.CacheBus.Subscribe(func(msg string) {
+		handler(msg)
+		b.got <- msg
+	})
+}
+
+func (b *notifyBus) wait(t *testing.T) {
+	select {
+	case <-b.got:
+	case <-time.After(time.Second):
+		t.Fatal("未收到缓存失效消息")
+	}
+}
+
+func TestRedisCacheBus(t *testing.T) {
+	mr := miniredis.RunT(t)
+	conf := redis.RedisConf{Host: mr.Addr(), Type: redis.NodeType}
+	busA, busB := NewRedisCacheBus(conf, "gorm-cache"), NewRedisCacheBus(conf, "gorm-cache")
+	defer busA.Close()
+	defer busB.Close()
+	l2 := newMemCache()
+	notifyB := &notifyBus{CacheBus: busB, got: make(chan string, 16)}
+	a := NewTwoLevelCache(l2, L1Conf{}, busA)
+	b := NewTwoLevelCache(l2, L1Conf{}, notifyB)
+	defer a.Close()
+	defer b.Close()
+
+	var v string
+	assert.Nil(t, a.Set("k1", "v1"))
+	notifyB.wait(t)
+	assert.Nil(t, a.Get("k1", &v))
+	assert.Nil(t, b.Get("k1", &v))
+	_, ok := a.l1.get("k1")
+	assert.True(t, ok)
+
+	// 其他实例通过 Redis 广播的失效消息清除本实例的进程内缓存，自身消息不清除
+	assert.Nil(t, b.Set("k1", "v2"))
+	assert.Eventually(t, func() bool {
+		_, ok := a.l1.get("k1")
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	_, ok = b.l1.get("k1")
+	assert.True(t, ok)
+	assert.Nil(t, a.Get("k1", &v))
+	assert.Equal(t, "v2", v)
+
+	// 迟到的旧消息不清除之后刷新的条目，较新的消息仍然清除
+	late := time.Now().Add(-time.Minute).UnixNano()
+	b.onInvalidate(`{"from":"other","keys":["k1"],"at":` + strconv.FormatInt(late, 10) + `}`)
+	_, ok = b.l1.get("k1")
+	assert.True(t, ok)
+	b.onInvalidate(`{"from":"other","keys":["k1"],"at":` + strconv.FormatInt(time.Now().UnixNano(), 10) + `}`)
+	_, ok = b.l1.get("k1")
+	assert.False(t, ok)
+
+	// 取消订阅后不再接收消息
+	a.Close()
+	assert.Nil(t, a.Set("k2", "v"))
+	assert.Nil(t, b.Del("k2"))
+	time.Sleep(50 * time.Millisecond)
+	_, ok = a.l1.get("k2")
+	assert.True(t, ok)
+}
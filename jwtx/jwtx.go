@@ -22,6 +22,7 @@ const (
 	CtxKeyJwtUserId       = "userId"    // 用户 ID
 	CtxKeyJwtUserName     = "userName"  // 用户名
 	CtxKeyJwtRoleId       = "roleId"    // 角色 ID
+	CtxKeyJwtTenantId     = "tenantId"  // 租户 ID
 	CtxKeyJwtNickName     = "nickName"  // 昵称
 	CtxKeyJwtToken        = "tokenStr"  // Token 字符串
 	CtxKeyRefreshAt       = "refreshAt" // 可刷新时间点（Unix 秒）
@@ -35,16 +36,17 @@ const (
 // SysJwtClaims 定义了 JWT 的自定义声明结构，包含用户身份信息和标准字段
 // 内嵌 jwt.RegisteredClaims 提供标准字段支持（exp、iat、iss 等）
 type SysJwtClaims struct {
-	UserId               string `json:"userId"`    // 用户 ID
-	RoleId               string `json:"roleId"`    // 角色 ID
-	DeptId               string `json:"deptId"`    // 部门 ID
-	UserName             string `json:"userName"`  // 用户名
-	NickName             string `json:"nickName"`  // 昵称
-	ClientId             string `json:"clientId"`  // 授权客户端 ID（必须）
-	Scope                string `json:"scope"`     // 授权范围（必须）
-	RefreshAt            int64  `json:"refreshAt"` // 可刷新时间点（Unix 秒）
-	Expire               int64  `json:"expire"`    // 令牌有效时长（秒）
-	TokenStr             string `json:"tokenStr"`  // 原始 Token 字符串（可选存储）
+	UserId               string `json:"userId"`             // 用户 ID
+	RoleId               string `json:"roleId"`             // 角色 ID
+	DeptId               string `json:"deptId"`             // 部门 ID
+	TenantId             string `json:"tenantId,omitempty"` // 租户 ID（多租户时使用）
+	UserName             string `json:"userName"`           // 用户名
+	NickName             string `json:"nickName"`           // 昵称
+	ClientId             string `json:"clientId"`           // 授权客户端 ID（必须）
+	Scope                string `json:"scope"`              // 授权范围（必须）
+	RefreshAt            int64  `json:"refreshAt"`          // 可刷新时间点（Unix 秒）
+	Expire               int64  `json:"expire"`             // 令牌有效时长（秒）
+	TokenStr             string `json:"tokenStr"`           // 原始 Token 字符串（可选存储）
 	jwt.RegisteredClaims        // 标准 JWT Claims：ExpiresAt、IssuedAt、Issuer 等
 }
 
@@ -171,6 +173,30 @@ func GetRoleIdFromCtx(ctx context.Context) string {
 	return ""
 }
 
+// GetTenantIdFromCtx 从上下文中获取 tenantId
+func GetTenantIdFromCtx(ctx context.Context) string {
+	if val, ok := ctx.Value(CtxKeyJwtTenantId).(string); ok {
+		return val
+	}
+	return ""
+}
+
+// headerTenantKey 上下文中请求头租户 ID 的键，与 jwt 中的 tenantId 分开保存
+type headerTenantKey struct{}
+
+// WithHeaderTenantId 将请求头中的租户 ID 写入上下文，该值未经签名校验，仅用于显式信任请求头的路由
+func WithHeaderTenantId(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, headerTenantKey{}, tenantId)
+}
+
+// GetHeaderTenantIdFromCtx 从上下文中获取请求头中的租户 ID
+func GetHeaderTenantIdFromCtx(ctx context.Context) string {
+	if val, ok := ctx.Value(headerTenantKey{}).(string); ok {
+		return val
+	}
+	return ""
+}
+
 // GetTokenStrFromCtx 从上下文中获取 tokenStr
 func GetTokenStrFromCtx(ctx context.Context) string {
 	if val, ok := ctx.Value(CtxKeyJwtToken).(string); ok {
@@ -0,0 +1,37 @@
+package middlewarex
+
+import (
+	"net/http"
+
+	"github.com/qiaogw/sub-sdk/jwtx"
+)
+
+// TenantHeader 默认的租户请求头
+const TenantHeader = "X-Tenant-Id"
+
+// TenantMiddleware 将请求头中的租户 id 写入上下文，与 jwt 中的 tenantId 分开保存（见 jwtx.GetHeaderTenantIdFromCtx）
+// 请求头可由客户端任意指定，仅用于显式信任请求头的路由，e.g. 内部调用、登录前的未认证路由；
+// 请求已携带 jwt 用户身份时忽略请求头，租户以 jwt 中的 tenantId 为准
+type TenantMiddleware struct {
+	header string
+}
+
+// NewTenantMiddleware 创建租户中间件，header 为空时使用 X-Tenant-Id
+func NewTenantMiddleware(header string) *TenantMiddleware {
+	if header == "" {
+		header = TenantHeader
+	}
+	return &TenantMiddleware{header: header}
+}
+
+func (m *TenantMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if jwtx.GetTenantIdFromCtx(ctx) == "" && jwtx.GetUserIdFromCtx(ctx) == "" {
+			if tenant := r.Header.Get(m.header); tenant != "" {
+				ctx = jwtx.WithHeaderTenantId(ctx, tenant)
+			}
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
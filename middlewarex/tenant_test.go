@@ -0,0 +1,37 @@
+package middlewarex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qiaogw/sub-sdk/jwtx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	var jwtTenant, headerTenant string
+	handle := NewTenantMiddleware("").Handle(func(w http.ResponseWriter, r *http.Request) {
+		jwtTenant = jwtx.GetTenantIdFromCtx(r.Context())
+		headerTenant = jwtx.GetHeaderTenantIdFromCtx(r.Context())
+	})
+	serve := func(ctx context.Context) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		r.Header.Set(TenantHeader, "t2")
+		handle(httptest.NewRecorder(), r)
+	}
+
+	// 未认证的请求使用请求头，与 jwt 中的租户分开保存
+	serve(context.Background())
+	assert.Equal(t, "", jwtTenant)
+	assert.Equal(t, "t2", headerTenant)
+
+	// 已认证的请求忽略请求头
+	serve(context.WithValue(context.Background(), jwtx.CtxKeyJwtTenantId, "t1"))
+	assert.Equal(t, "t1", jwtTenant)
+	assert.Equal(t, "", headerTenant)
+	serve(context.WithValue(context.Background(), jwtx.CtxKeyJwtUserId, "1"))
+	assert.Equal(t, "", jwtTenant)
+	assert.Equal(t, "", headerTenant)
+}
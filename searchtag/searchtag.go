@@ -0,0 +1,146 @@
+// Package searchtag 解析查询结构体的 search 标签，只依赖标准库，供 gormx 与 utils 共用
+package searchtag
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Key search 标签名
+const Key = "search"
+
+// Tag 解析后的 search 标签
+type Tag struct {
+	Type    string
+	Column  string
+	Table   string
+	On      [][]string // 关联字段对，可设置多个 on，e.g. on:id:dept_id;on:tenant_id:tenant_id
+	Join    string
+	Alias   string   // 关联表别名，用于同表多次关联或自关联
+	Cond    []string // 关联 ON 中附加的固定条件，e.g. cond:p.deleted_at IS NULL
+	Group   string   // 条件分组，相同分组的字段以括号包裹，"." 分隔表示嵌套分组
+	Logic   string   // 连接方式 and/or，作用于所在分组；未分组时 or 写入 Or 条件
+	Path    string   // JSON 路径，"." 分隔，用于 jsoneq/jsoncontains
+	Ops     []string // 前端过滤额外允许的操作符，"|" 分隔，e.g. ops:icontains|startswith
+	Keyword bool     // 参与关键字模糊查询，用于 KeywordSearch 的字段白名单
+}
+
+// Parse 解析 search 标签
+func Parse(tag string) *Tag {
+	r := &Tag{}
+	tags := strings.Split(tag, ";")
+	var ts []string
+	for _, t := range tags {
+		ts = strings.Split(t, ":")
+		if len(ts) == 0 {
+			continue
+		}
+		switch ts[0] {
+		case "type":
+			if len(ts) > 1 {
+				r.Type = ts[1]
+			}
+		case "column":
+			if len(ts) > 1 {
+				r.Column = ts[1]
+			}
+		case "table":
+			if len(ts) > 1 {
+				r.Table = ts[1]
+			}
+		case "on":
+			if len(ts) > 1 {
+				r.On = append(r.On, ts[1:])
+			}
+		case "join":
+			if len(ts) > 1 {
+				r.Join = ts[1]
+			}
+		case "group":
+			if len(ts) > 1 {
+				r.Group = ts[1]
+			}
+		case "logic":
+			if len(ts) > 1 {
+				r.Logic = strings.ToLower(ts[1])
+			}
+		case "alias":
+			if len(ts) > 1 {
+				r.Alias = ts[1]
+			}
+		case "cond":
+			if len(ts) > 1 {
+				r.Cond = append(r.Cond, strings.Join(ts[1:], ":"))
+			}
+		case "path":
+			if len(ts) > 1 {
+				r.Path = ts[1]
+			}
+		case "ops":
+			if len(ts) > 1 {
+				r.Ops = strings.Split(strings.ToLower(ts[1]), "|")
+			}
+		case "keyword":
+			r.Keyword = true
+		}
+	}
+	return r
+}
+
+// Column 按 json 字段名查找 search 标签声明的列，关联字段以 "." 分隔，e.g. dept.name
+// q 不是结构体或字段未声明 column 时返回错误
+func Column(q interface{}, field string) (string, error) {
+	typ := reflect.TypeOf(q)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return "", fmt.Errorf("search 查询对象必须为结构体: %v", typ)
+	}
+	if column := lookup(typ, strings.Split(field, ".")); column != "" {
+		return column, nil
+	}
+	return "", fmt.Errorf("search 标签未声明字段: %s", field)
+}
+
+// lookup 按声明顺序查找字段，未设置 search 标签的结构体字段递归展开
+func lookup(typ reflect.Type, path []string) string {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag, ok := sf.Tag.Lookup(Key)
+		if !ok {
+			if sf.Type.Kind() == reflect.Struct {
+				if column := lookup(sf.Type, path); column != "" {
+					return column
+				}
+			}
+			continue
+		}
+		if tag == "-" || JSONName(sf) != path[0] {
+			continue
+		}
+		t := Parse(tag)
+		if t.Join != "" {
+			if len(path) > 1 && sf.Type.Kind() == reflect.Struct {
+				if column := lookup(sf.Type, path[1:]); column != "" {
+					return column
+				}
+			}
+			continue
+		}
+		if len(path) == 1 && t.Column != "" {
+			return t.Column
+		}
+	}
+	return ""
+}
+
+// JSONName 字段的 json 名称，未设置时使用字段名
+func JSONName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
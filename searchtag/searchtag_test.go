@@ -0,0 +1,38 @@
+package searchtag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumn(t *testing.T) {
+	type dept struct {
+		Name string `json:"name" search:"type:exact;column:name"`
+	}
+	type base struct {
+		Status int64 `json:"status" search:"type:exact;table:user;column:status"`
+	}
+	type query struct {
+		base
+		Code string `json:"code" search:"table:user;column:code"`
+		Dept dept   `json:"dept" search:"type:left;table:user;join:dept;on:id:dept_id;on:tenant_id:tenant_id"`
+		Skip string `json:"skip" search:"-"`
+	}
+	tag := Parse("type:left;join:dept;on:id:dept_id;on:tenant_id:tenant_id;ops:Contains|in;keyword")
+	assert.Equal(t, [][]string{{"id", "dept_id"}, {"tenant_id", "tenant_id"}}, tag.On)
+	assert.Equal(t, []string{"contains", "in"}, tag.Ops)
+	assert.True(t, tag.Keyword)
+
+	for field, column := range map[string]string{"code": "code", "status": "status", "dept.name": "name"} {
+		got, err := Column(&query{}, field)
+		assert.Nil(t, err)
+		assert.Equal(t, column, got)
+	}
+	for _, field := range []string{"skip", "dept", "password"} {
+		_, err := Column(query{}, field)
+		assert.NotNil(t, err, field)
+	}
+	_, err := Column("code", "code")
+	assert.NotNil(t, err)
+}